@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
 	"github.com/christophercampbell/riskr/pkg/sim"
 	"github.com/urfave/cli/v2"
 	"strings"
@@ -29,6 +30,10 @@ var commands = &cli.App{
 			Name:   "streamer",
 			Usage:  "Start the streamer server",
 			Action: runStreamer,
+		}, {
+			Name:   "webhook",
+			Usage:  "Start the webhook dispatcher",
+			Action: runWebhook,
 		}, {
 			Name:  "policy",
 			Usage: "Apply or list policies",
@@ -59,6 +64,54 @@ var commands = &cli.App{
 					}},
 				},
 			},
+		}, {
+			Name:  "sanctions",
+			Usage: "Manage sanctions lists",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "import",
+					Usage:     "Import an OFAC SDN export into a sanctions list file",
+					ArgsUsage: "<file>",
+					Action:    sanctionsImport,
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "format",
+							Usage:    fmt.Sprintf("source format [%s|%s]", sanctions.FormatSDNXML, sanctions.FormatSDNCSV),
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:    "out",
+							Aliases: []string{"o"},
+							Usage:   "list file to write (default: config sanctions.file)",
+						},
+						&cli.StringFlag{
+							Name:  "names-out",
+							Usage: "party names file to write for name screening (default: <out>.names)",
+						},
+					},
+				}, {
+					Name:      "publish",
+					Usage:     "Publish a list version to the object store for hot reload",
+					ArgsUsage: "<file>",
+					Action:    sanctionsPublish,
+					Flags: []cli.Flag{&cli.StringFlag{
+						Name:     "name",
+						Aliases:  []string{"n"},
+						Usage:    "screening list name as declared in the policy",
+						Required: true,
+					}},
+				}, {
+					Name:      "stats",
+					Usage:     "Report screening index memory and lookup latency for a list file",
+					ArgsUsage: "<file>",
+					Action:    sanctionsStats,
+					Flags: []cli.Flag{&cli.IntFlag{
+						Name:  "probes",
+						Usage: "lookups per measurement",
+						Value: 1000000,
+					}},
+				},
+			},
 		}, {
 			Name:   "sim",
 			Usage:  "Run a simulation scenario",
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/natsjs"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+	"github.com/nats-io/nats.go"
+	"github.com/urfave/cli/v2"
+)
+
+func sanctionsImport(cli *cli.Context) error {
+	cfg, logger, err := load(cli)
+	if err != nil {
+		return err
+	}
+	if cli.NArg() != 1 {
+		return errors.New("sanctions import: expected exactly one source file")
+	}
+	src, err := os.Open(cli.Args().First())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	now := time.Now()
+	format := cli.String("format")
+	version, entries, names, err := sanctions.Import(format, src, now)
+	if err != nil {
+		return err
+	}
+
+	out := cli.String("out")
+	if out == "" {
+		out = cfg.ResolvePath(cfg.Sanctions.File)
+	}
+	namesOut := cli.String("names-out")
+	if namesOut == "" {
+		namesOut = out + ".names"
+	}
+
+	var buf bytes.Buffer
+	if err = sanctions.WriteList(&buf, version, "OFAC SDN ("+format+")", now, entries); err != nil {
+		return err
+	}
+	_, next, invalid, err := sanctions.Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		return err
+	}
+	for _, ln := range invalid {
+		logger.Warn("sanctions import: address failed validation (kept)", "entry", ln)
+	}
+	prev := map[string]struct{}{}
+	if f, err := os.Open(out); err == nil {
+		_, prev, _, err = sanctions.Parse(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	added, removed := sanctions.Diff(prev, next)
+
+	if err = writeAtomic(out, buf.Bytes()); err != nil {
+		return err
+	}
+	var nbuf bytes.Buffer
+	if err = sanctions.WriteNames(&nbuf, version, "OFAC SDN names ("+format+")", names); err != nil {
+		return err
+	}
+	if err = writeAtomic(namesOut, nbuf.Bytes()); err != nil {
+		return err
+	}
+
+	for _, a := range added {
+		fmt.Println("+", a)
+	}
+	for _, a := range removed {
+		fmt.Println("-", a)
+	}
+	logger.Info("sanctions imported", "file", out, "version", version, "entries", len(entries), "added", len(added), "removed", len(removed),
+		"names_file", namesOut, "names", len(names))
+	return nil
+}
+
+// writeAtomic replaces path with b so running services never read a partial file.
+func writeAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Close()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func sanctionsPublish(cli *cli.Context) error {
+	cfg, logger, err := load(cli)
+	if err != nil {
+		return err
+	}
+	if cli.NArg() != 1 {
+		return errors.New("sanctions publish: expected exactly one list file")
+	}
+	b, err := os.ReadFile(cli.Args().First())
+	if err != nil {
+		return err
+	}
+	nc, err := natsjs.Connect(cli.Context, cfg.NATS.URLs, nats.Name("riskr-sanctions-publish"))
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+	js, err := natsjs.JetStream(nc)
+	if err != nil {
+		return err
+	}
+	obs, err := natsjs.ObjectStore(js, natsjs.BucketSanctions)
+	if err != nil {
+		return err
+	}
+	l, err := sanctions.Publish(obs, cli.String("name"), b)
+	if err != nil {
+		return err
+	}
+	logger.Info("sanctions list published", "list", l.Name, "version", l.Version, "entries", l.Len(), "source", l.Source)
+	return nil
+}
+
+func sanctionsStats(cli *cli.Context) error {
+	_, _, err := load(cli)
+	if err != nil {
+		return err
+	}
+	if cli.NArg() != 1 {
+		return errors.New("sanctions stats: expected exactly one list file")
+	}
+	path := cli.Args().First()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	l, err := sanctions.LoadFile(filepath.Base(path), path)
+	if err != nil {
+		return err
+	}
+	loadDur := time.Since(start)
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	_, keys, _, err := sanctions.Parse(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	hits := make([]string, 0, len(keys))
+	for k := range keys {
+		hits = append(hits, k)
+	}
+	misses := make([]string, len(hits))
+	for i := range misses {
+		misses[i] = fmt.Sprintf("EVM:0x%040x", i)
+	}
+
+	ix := l.Index()
+	probe := func(ks []string) time.Duration {
+		n := cli.Int("probes")
+		if len(ks) == 0 || n == 0 {
+			return 0
+		}
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			ix.Has(ks[i%len(ks)])
+		}
+		return time.Since(start) / time.Duration(n)
+	}
+
+	fmt.Printf("list:          %s (version %s)\n", path, l.Version)
+	fmt.Printf("entries:       %d (invalid %d)\n", l.Len(), l.Invalid)
+	fmt.Printf("load:          %s\n", loadDur)
+	fmt.Printf("index bytes:   %d\n", ix.SizeBytes())
+	fmt.Printf("heap delta:    %d\n", int64(after.HeapAlloc)-int64(before.HeapAlloc))
+	fmt.Printf("lookup (hit):  %s\n", probe(hits))
+	fmt.Printf("lookup (miss): %s\n", probe(misses))
+	runtime.KeepAlive(l)
+	return nil
+}
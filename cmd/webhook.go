@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/christophercampbell/riskr/pkg/webhook"
+	"github.com/urfave/cli/v2"
+)
+
+func runWebhook(cli *cli.Context) error {
+	cfg, logger, err := load(cli)
+	if err != nil {
+		return err
+	}
+	if err = webhook.Run(cli.Context, cfg, logger); err != nil {
+		logger.Error("webhook dispatcher exited", "err", err)
+		return err
+	}
+	return nil
+}
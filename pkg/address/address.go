@@ -0,0 +1,302 @@
+// Package address normalizes and validates blockchain addresses per chain so
+// screening lists and events agree on a single (chain, address) key.
+//
+// EVM hex addresses are case-insensitive (mixed case must carry a valid EIP-55
+// checksum) and normalize to lower case; bech32/bech32m addresses normalize to
+// lower case; base58 formats (BTC legacy, Tron, Solana, ...) are case-sensitive
+// and kept verbatim.
+package address
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Chain families. All EVM chains share an address space and therefore a family.
+const (
+	EVM     = "EVM"
+	BTC     = "BTC"
+	LTC     = "LTC"
+	TRX     = "TRX"
+	SOL     = "SOL"
+	Unknown = ""
+)
+
+var (
+	ErrInvalid  = errors.New("invalid address")
+	ErrChecksum = errors.New("address checksum mismatch")
+)
+
+// aliases maps chain codes (ours, SDN's "Digital Currency Address - XXX") to families.
+var aliases = map[string]string{
+	"EVM": EVM, "ETH": EVM, "ETC": EVM, "ARB": EVM, "BSC": EVM, "MATIC": EVM, "POLYGON": EVM, "OP": EVM, "BASE": EVM,
+	"BTC": BTC, "XBT": BTC,
+	"LTC": LTC,
+	"TRX": TRX, "TRON": TRX,
+	"SOL": SOL,
+}
+
+// Key identifies an address within its chain family.
+type Key struct {
+	Chain   string
+	Address string
+}
+
+func (k Key) String() string { return k.Chain + ":" + k.Address }
+
+// Family returns the chain family for a chain code, or Unknown.
+func Family(chain string) string {
+	return aliases[strings.ToUpper(strings.TrimSpace(chain))]
+}
+
+// Normalize validates addr for chain and returns its canonical key. chain may
+// be empty or a non-chain label (e.g. a token code, "INLINE"), in which case the
+// family is detected from the address format. A best-effort key is returned
+// even when err != nil, so screening can still match malformed input.
+func Normalize(chain, addr string) (Key, error) {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return Key{}, ErrInvalid
+	}
+	fam := Family(chain)
+	if fam == Unknown {
+		fam = detect(addr)
+	}
+	switch fam {
+	case EVM:
+		return Key{EVM, strings.ToLower(addr)}, validateEVM(addr)
+	case BTC, LTC:
+		if hrp, err := validateBech32(addr); err == nil {
+			if f := bech32Family(hrp); f != fam {
+				return Key{fam, strings.ToLower(addr)}, fmt.Errorf("%w: bech32 prefix %q is not %s", ErrInvalid, hrp, fam)
+			}
+			return Key{fam, strings.ToLower(addr)}, nil
+		}
+		_, err := base58Check(addr)
+		return Key{fam, addr}, err
+	case TRX:
+		payload, err := base58Check(addr)
+		if err == nil && (len(payload) != 21 || payload[0] != 0x41) {
+			err = fmt.Errorf("%w: not a tron address", ErrInvalid)
+		}
+		return Key{TRX, addr}, err
+	case SOL:
+		b, err := base58Decode(addr)
+		if err == nil && len(b) != 32 {
+			err = fmt.Errorf("%w: solana address must decode to 32 bytes", ErrInvalid)
+		}
+		return Key{SOL, addr}, err
+	default:
+		// other bech32 families (e.g. bnb1...) are case-insensitive
+		if hrp, err := validateBech32(addr); err == nil {
+			return Key{strings.ToUpper(hrp), strings.ToLower(addr)}, nil
+		}
+		// hex-looking but not a valid EVM address: stay case-insensitive
+		if strings.HasPrefix(strings.ToLower(addr), "0x") {
+			return Key{Unknown, strings.ToLower(addr)}, nil
+		}
+		return Key{Unknown, addr}, nil
+	}
+}
+
+// detect guesses the family from the address format alone.
+func detect(addr string) string {
+	if isHex40(addr) {
+		return EVM
+	}
+	if hrp, err := validateBech32(addr); err == nil {
+		if f := bech32Family(hrp); f != Unknown {
+			return f
+		}
+		return Unknown
+	}
+	if payload, err := base58Check(addr); err == nil {
+		switch {
+		case len(payload) == 21 && payload[0] == 0x41:
+			return TRX
+		case len(payload) == 21 && (payload[0] == 0x00 || payload[0] == 0x05):
+			return BTC
+		case len(payload) == 21 && (payload[0] == 0x30 || payload[0] == 0x32):
+			return LTC
+		}
+		return Unknown
+	}
+	if b, err := base58Decode(addr); err == nil && len(b) == 32 {
+		return SOL
+	}
+	return Unknown
+}
+
+func bech32Family(hrp string) string {
+	switch hrp {
+	case "bc", "tb", "bcrt":
+		return BTC
+	case "ltc", "tltc":
+		return LTC
+	}
+	return Unknown
+}
+
+// ------------------------ EVM / EIP-55 ------------------------
+
+func isHex40(addr string) bool {
+	if len(addr) != 42 || (addr[:2] != "0x" && addr[:2] != "0X") {
+		return false
+	}
+	_, err := hex.DecodeString(addr[2:])
+	return err == nil
+}
+
+func validateEVM(addr string) error {
+	if !isHex40(addr) {
+		return fmt.Errorf("%w: expected 0x + 40 hex chars", ErrInvalid)
+	}
+	body := addr[2:]
+	lower := strings.ToLower(body)
+	if body == lower || body == strings.ToUpper(body) {
+		return nil // no checksum to verify
+	}
+	if body != eip55(lower) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// eip55 returns the checksummed form of a lower-case 40-char hex address body.
+func eip55(lower string) string {
+	h := sha3.NewLegacyKeccak256()
+	h.Write([]byte(lower))
+	sum := h.Sum(nil)
+	out := []byte(lower)
+	for i, c := range out {
+		if c < 'a' || c > 'f' {
+			continue
+		}
+		nib := sum[i/2]
+		if i%2 == 0 {
+			nib >>= 4
+		}
+		if nib&0x0f >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return string(out)
+}
+
+// ------------------------ bech32 / bech32m ------------------------
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// validateBech32 checks a bech32/bech32m string (BIP-173/BIP-350) and returns its hrp.
+func validateBech32(addr string) (string, error) {
+	if len(addr) < 8 || len(addr) > 90 {
+		return "", ErrInvalid
+	}
+	lower := strings.ToLower(addr)
+	if addr != lower && addr != strings.ToUpper(addr) {
+		return "", fmt.Errorf("%w: mixed-case bech32", ErrInvalid)
+	}
+	pos := strings.LastIndexByte(lower, '1')
+	if pos < 1 || pos+7 > len(lower) {
+		return "", ErrInvalid
+	}
+	hrp := lower[:pos]
+	data := make([]byte, 0, len(lower)-pos-1)
+	for _, c := range lower[pos+1:] {
+		d := strings.IndexRune(bech32Charset, c)
+		if d < 0 {
+			return "", ErrInvalid
+		}
+		data = append(data, byte(d))
+	}
+	chk := bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	if chk != bech32Const && chk != bech32mConst {
+		return "", ErrChecksum
+	}
+	// segwit: witness v0 uses bech32, v1+ bech32m
+	if f := bech32Family(hrp); f != Unknown && len(data) > 6 {
+		if (data[0] == 0) != (chk == bech32Const) {
+			return "", fmt.Errorf("%w: wrong bech32 variant for witness v%d", ErrChecksum, data[0])
+		}
+	}
+	return hrp, nil
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// ------------------------ base58 / base58check ------------------------
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func base58Decode(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		d := strings.IndexRune(base58Alphabet, c)
+		if d < 0 {
+			return nil, fmt.Errorf("%w: not base58", ErrInvalid)
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(d)))
+	}
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+// base58Check decodes s and verifies its 4-byte double-SHA256 checksum,
+// returning the payload (version byte included).
+func base58Check(s string) ([]byte, error) {
+	b, err := base58Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 5 {
+		return nil, ErrInvalid
+	}
+	payload, sum := b[:len(b)-4], b[len(b)-4:]
+	h1 := sha256.Sum256(payload)
+	h2 := sha256.Sum256(h1[:])
+	if string(h2[:4]) != string(sum) {
+		return nil, ErrChecksum
+	}
+	return payload, nil
+}
@@ -0,0 +1,91 @@
+package address
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, tc := range []struct {
+		name, chain, addr string
+		want              Key
+		err               error // nil: valid; ErrInvalid/ErrChecksum: errors.Is
+	}{
+		// EIP-55 examples
+		{"eip55", "ETH", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Key{EVM, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}, nil},
+		{"eip55", "ETH", "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359", Key{EVM, "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"}, nil},
+		{"eip55", "BSC", "0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB", Key{EVM, "0xdbf03b407c01e7cd3cbea99509d93f8dddc8c6fb"}, nil},
+		{"eip55 detected", "", "0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb", Key{EVM, "0xd1220a0cf47c7b9be7a2e6ba89f429762e7b9adb"}, nil},
+		{"evm lower", "ETH", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", Key{EVM, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}, nil},
+		{"evm upper", "ETH", "0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", Key{EVM, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}, nil},
+		{"eip55 bad mixed case", "ETH", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", Key{EVM, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}, ErrChecksum},
+		{"evm short", "ETH", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea", Key{EVM, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea"}, ErrInvalid},
+
+		// BIP-173 valid
+		{"bip173 upper", "BTC", "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", Key{BTC, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"}, nil},
+		{"bip173 testnet", "", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7", Key{BTC, "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sl5k7"}, nil},
+		// BIP-350 valid (witness v1 taproot, bech32m)
+		{"bip350 taproot", "BTC", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0", Key{BTC, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0"}, nil},
+		{"bip350 detected", "", "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", Key{BTC, "bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y"}, nil},
+		// BIP-173 / BIP-350 invalid
+		{"bip173 mixed case", "BTC", "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sL5k7", Key{BTC, "tb1qrp33g0q5c5txsp9arysrx4k6zdkfs4nce4xj0gdcccefvpysxf3q0sL5k7"}, ErrInvalid},
+		{"bip173 bad checksum", "BTC", "bc1zw508d6qejxtdg4y5r3zarvaryvqyzf3du", Key{BTC, "bc1zw508d6qejxtdg4y5r3zarvaryvqyzf3du"}, ErrInvalid},
+		{"bip350 v1 with bech32", "BTC", "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd", Key{BTC, "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqh2y7hd"}, ErrInvalid},
+		{"bip350 v0 with bech32m", "BTC", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh", Key{BTC, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh"}, ErrInvalid},
+		{"bech32 wrong family", "LTC", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", Key{LTC, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"}, ErrInvalid},
+
+		// base58check
+		{"p2pkh", "BTC", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", Key{BTC, "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"}, nil},
+		{"p2sh detected", "", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", Key{BTC, "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy"}, nil},
+		{"p2pkh bad checksum", "BTC", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3", Key{BTC, "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN3"}, ErrChecksum},
+		{"p2pkh case kept", "BTC", "1bvbmseystwetqtfn5au4m4gfg7xjanvn2", Key{BTC, "1bvbmseystwetqtfn5au4m4gfg7xjanvn2"}, ErrChecksum},
+
+		// Tron
+		{"tron", "TRON", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", Key{TRX, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"}, nil},
+		{"tron detected", "", "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", Key{TRX, "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"}, nil},
+		{"tron is not btc", "TRX", "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", Key{TRX, "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"}, ErrInvalid},
+
+		// Solana
+		{"solana", "SOL", "EPjFWdd5AufqSSqeM1qDTpwstgLiPfV6YG2cUGJ8nPYS", Key{SOL, "EPjFWdd5AufqSSqeM1qDTpwstgLiPfV6YG2cUGJ8nPYS"}, nil},
+		{"solana detected", "", "11111111111111111111111111111111", Key{SOL, "11111111111111111111111111111111"}, nil},
+		{"solana short", "SOL", "EPjFWdd5AufqSSqeM1qDTpwstgLiPfV6", Key{SOL, "EPjFWdd5AufqSSqeM1qDTpwstgLiPfV6"}, ErrInvalid},
+
+		// unknown chains keep a best-effort key without error
+		{"unknown chain evm", "INLINE", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Key{EVM, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"}, nil},
+		{"unknown chain hex", "DOGE", "0xABCdef", Key{Unknown, "0xabcdef"}, nil},
+		{"unknown chain verbatim", "XRP", "rEb8TK3gBgk5auZkwc6sHnwrGVJH8DuaLh", Key{Unknown, "rEb8TK3gBgk5auZkwc6sHnwrGVJH8DuaLh"}, nil},
+		{"empty", "ETH", "  ", Key{}, ErrInvalid},
+	} {
+		got, err := Normalize(tc.chain, tc.addr)
+		if tc.err == nil && err != nil || tc.err != nil && !errors.Is(err, tc.err) {
+			t.Errorf("%s: Normalize(%q, %q) err = %v, want %v", tc.name, tc.chain, tc.addr, err, tc.err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: Normalize(%q, %q) = %v, want %v", tc.name, tc.chain, tc.addr, got, tc.want)
+		}
+	}
+}
+
+func TestFamily(t *testing.T) {
+	for chain, want := range map[string]string{
+		"eth": EVM, " polygon ": EVM, "XBT": BTC, "ltc": LTC, "Tron": TRX, "SOL": SOL, "DOGE": Unknown, "": Unknown,
+	} {
+		if got := Family(chain); got != want {
+			t.Errorf("Family(%q) = %q, want %q", chain, got, want)
+		}
+	}
+}
+
+func TestEIP55RoundTrip(t *testing.T) {
+	for _, addr := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	} {
+		if got := "0x" + eip55(strings.ToLower(addr[2:])); got != addr {
+			t.Errorf("eip55(%s) = %s", addr, got)
+		}
+	}
+}
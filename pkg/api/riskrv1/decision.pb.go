@@ -0,0 +1,1131 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: riskr/v1/decision.proto
+
+// Decision API; messages mirror the gateway's JSON DecisionReq/DecisionResp
+// and the DecisionEvent published on NATS.
+
+package riskrv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Subject struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	AccountId     string                 `protobuf:"bytes,2,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Addresses     []string               `protobuf:"bytes,3,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	GeoIso        string                 `protobuf:"bytes,4,opt,name=geo_iso,json=geoIso,proto3" json:"geo_iso,omitempty"`
+	KycLevel      string                 `protobuf:"bytes,5,opt,name=kyc_level,json=kycLevel,proto3" json:"kyc_level,omitempty"`
+	Name          string                 `protobuf:"bytes,6,opt,name=name,proto3" json:"name,omitempty"`
+	Dob           string                 `protobuf:"bytes,7,opt,name=dob,proto3" json:"dob,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Subject) Reset() {
+	*x = Subject{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Subject) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subject) ProtoMessage() {}
+
+func (x *Subject) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subject.ProtoReflect.Descriptor instead.
+func (*Subject) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Subject) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Subject) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *Subject) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *Subject) GetGeoIso() string {
+	if x != nil {
+		return x.GeoIso
+	}
+	return ""
+}
+
+func (x *Subject) GetKycLevel() string {
+	if x != nil {
+		return x.KycLevel
+	}
+	return ""
+}
+
+func (x *Subject) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Subject) GetDob() string {
+	if x != nil {
+		return x.Dob
+	}
+	return ""
+}
+
+type Tx struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // withdraw|deposit
+	Asset         string                 `protobuf:"bytes,2,opt,name=asset,proto3" json:"asset,omitempty"`
+	Amount        string                 `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"` // base units
+	UsdValue      float64                `protobuf:"fixed64,4,opt,name=usd_value,json=usdValue,proto3" json:"usd_value,omitempty"`
+	DestAddress   string                 `protobuf:"bytes,5,opt,name=dest_address,json=destAddress,proto3" json:"dest_address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Tx) Reset() {
+	*x = Tx{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Tx) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tx) ProtoMessage() {}
+
+func (x *Tx) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tx.ProtoReflect.Descriptor instead.
+func (*Tx) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Tx) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Tx) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+func (x *Tx) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *Tx) GetUsdValue() float64 {
+	if x != nil {
+		return x.UsdValue
+	}
+	return 0
+}
+
+func (x *Tx) GetDestAddress() string {
+	if x != nil {
+		return x.DestAddress
+	}
+	return ""
+}
+
+type CheckRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // idempotency key
+	Subject       *Subject               `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	Tx            *Tx                    `protobuf:"bytes,3,opt,name=tx,proto3" json:"tx,omitempty"`
+	Context       *structpb.Struct       `protobuf:"bytes,4,opt,name=context,proto3" json:"context,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CheckRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetSubject() *Subject {
+	if x != nil {
+		return x.Subject
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetTx() *Tx {
+	if x != nil {
+		return x.Tx
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetContext() *structpb.Struct {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+type Evidence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuleId        string                 `protobuf:"bytes,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value         *structpb.Value        `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	Limit         *structpb.Value        `protobuf:"bytes,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	List          string                 `protobuf:"bytes,5,opt,name=list,proto3" json:"list,omitempty"`
+	ListVersion   string                 `protobuf:"bytes,6,opt,name=list_version,json=listVersion,proto3" json:"list_version,omitempty"`
+	Degraded      bool                   `protobuf:"varint,7,opt,name=degraded,proto3" json:"degraded,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Evidence) Reset() {
+	*x = Evidence{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Evidence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Evidence) ProtoMessage() {}
+
+func (x *Evidence) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Evidence.ProtoReflect.Descriptor instead.
+func (*Evidence) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Evidence) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *Evidence) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Evidence) GetValue() *structpb.Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Evidence) GetLimit() *structpb.Value {
+	if x != nil {
+		return x.Limit
+	}
+	return nil
+}
+
+func (x *Evidence) GetList() string {
+	if x != nil {
+		return x.List
+	}
+	return ""
+}
+
+func (x *Evidence) GetListVersion() string {
+	if x != nil {
+		return x.ListVersion
+	}
+	return ""
+}
+
+func (x *Evidence) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+type CheckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DecisionId    string                 `protobuf:"bytes,1,opt,name=decision_id,json=decisionId,proto3" json:"decision_id,omitempty"`
+	EventId       string                 `protobuf:"bytes,2,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Decision      string                 `protobuf:"bytes,3,opt,name=decision,proto3" json:"decision,omitempty"`
+	DecisionCode  string                 `protobuf:"bytes,4,opt,name=decision_code,json=decisionCode,proto3" json:"decision_code,omitempty"`
+	PolicyVersion string                 `protobuf:"bytes,5,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	Evidence      []*Evidence            `protobuf:"bytes,6,rep,name=evidence,proto3" json:"evidence,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Replayed      bool                   `protobuf:"varint,8,opt,name=replayed,proto3" json:"replayed,omitempty"`
+	Cached        bool                   `protobuf:"varint,9,opt,name=cached,proto3" json:"cached,omitempty"`
+	Degraded      bool                   `protobuf:"varint,10,opt,name=degraded,proto3" json:"degraded,omitempty"` // some rules used their fallback decision, see evidence
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckResponse) Reset() {
+	*x = CheckResponse{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResponse) ProtoMessage() {}
+
+func (x *CheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResponse.ProtoReflect.Descriptor instead.
+func (*CheckResponse) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CheckResponse) GetDecisionId() string {
+	if x != nil {
+		return x.DecisionId
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetDecision() string {
+	if x != nil {
+		return x.Decision
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetDecisionCode() string {
+	if x != nil {
+		return x.DecisionCode
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetPolicyVersion() string {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetEvidence() []*Evidence {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
+func (x *CheckResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *CheckResponse) GetReplayed() bool {
+	if x != nil {
+		return x.Replayed
+	}
+	return false
+}
+
+func (x *CheckResponse) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *CheckResponse) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+type CheckBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*CheckRequest        `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckBatchRequest) Reset() {
+	*x = CheckBatchRequest{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckBatchRequest) ProtoMessage() {}
+
+func (x *CheckBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckBatchRequest.ProtoReflect.Descriptor instead.
+func (*CheckBatchRequest) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CheckBatchRequest) GetItems() []*CheckRequest {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type BatchItem struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Index int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// Types that are valid to be assigned to Outcome:
+	//
+	//	*BatchItem_Result
+	//	*BatchItem_Error
+	Outcome       isBatchItem_Outcome `protobuf_oneof:"outcome"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItem) Reset() {
+	*x = BatchItem{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItem) ProtoMessage() {}
+
+func (x *BatchItem) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItem.ProtoReflect.Descriptor instead.
+func (*BatchItem) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchItem) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BatchItem) GetOutcome() isBatchItem_Outcome {
+	if x != nil {
+		return x.Outcome
+	}
+	return nil
+}
+
+func (x *BatchItem) GetResult() *CheckResponse {
+	if x != nil {
+		if x, ok := x.Outcome.(*BatchItem_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+func (x *BatchItem) GetError() string {
+	if x != nil {
+		if x, ok := x.Outcome.(*BatchItem_Error); ok {
+			return x.Error
+		}
+	}
+	return ""
+}
+
+type isBatchItem_Outcome interface {
+	isBatchItem_Outcome()
+}
+
+type BatchItem_Result struct {
+	Result *CheckResponse `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+type BatchItem_Error struct {
+	Error string `protobuf:"bytes,3,opt,name=error,proto3,oneof"`
+}
+
+func (*BatchItem_Result) isBatchItem_Outcome() {}
+
+func (*BatchItem_Error) isBatchItem_Outcome() {}
+
+type CheckBatchResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BatchId       string                 `protobuf:"bytes,1,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	PolicyVersion string                 `protobuf:"bytes,2,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	Results       []*BatchItem           `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CheckBatchResponse) Reset() {
+	*x = CheckBatchResponse{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CheckBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckBatchResponse) ProtoMessage() {}
+
+func (x *CheckBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckBatchResponse.ProtoReflect.Descriptor instead.
+func (*CheckBatchResponse) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CheckBatchResponse) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *CheckBatchResponse) GetPolicyVersion() string {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return ""
+}
+
+func (x *CheckBatchResponse) GetResults() []*BatchItem {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type DecisionEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SchemaVersion string                 `protobuf:"bytes,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	DecisionId    string                 `protobuf:"bytes,2,opt,name=decision_id,json=decisionId,proto3" json:"decision_id,omitempty"`
+	EventId       string                 `protobuf:"bytes,3,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	UserId        string                 `protobuf:"bytes,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	IssuedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=issued_at,json=issuedAt,proto3" json:"issued_at,omitempty"`
+	Stage         string                 `protobuf:"bytes,6,opt,name=stage,proto3" json:"stage,omitempty"` // provisional|final|override
+	Decision      string                 `protobuf:"bytes,7,opt,name=decision,proto3" json:"decision,omitempty"`
+	DecisionCode  string                 `protobuf:"bytes,8,opt,name=decision_code,json=decisionCode,proto3" json:"decision_code,omitempty"`
+	PolicyVersion string                 `protobuf:"bytes,9,opt,name=policy_version,json=policyVersion,proto3" json:"policy_version,omitempty"`
+	Evidence      []*Evidence            `protobuf:"bytes,10,rep,name=evidence,proto3" json:"evidence,omitempty"`
+	BatchId       string                 `protobuf:"bytes,11,opt,name=batch_id,json=batchId,proto3" json:"batch_id,omitempty"`
+	ClientId      string                 `protobuf:"bytes,12,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"` // authenticated API client of the check
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecisionEvent) Reset() {
+	*x = DecisionEvent{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecisionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecisionEvent) ProtoMessage() {}
+
+func (x *DecisionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecisionEvent.ProtoReflect.Descriptor instead.
+func (*DecisionEvent) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DecisionEvent) GetSchemaVersion() string {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetDecisionId() string {
+	if x != nil {
+		return x.DecisionId
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetIssuedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.IssuedAt
+	}
+	return nil
+}
+
+func (x *DecisionEvent) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetDecision() string {
+	if x != nil {
+		return x.Decision
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetDecisionCode() string {
+	if x != nil {
+		return x.DecisionCode
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetPolicyVersion() string {
+	if x != nil {
+		return x.PolicyVersion
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetEvidence() []*Evidence {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
+func (x *DecisionEvent) GetBatchId() string {
+	if x != nil {
+		return x.BatchId
+	}
+	return ""
+}
+
+func (x *DecisionEvent) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+type DecisionHistory struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EventId       string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	Current       *DecisionEvent         `protobuf:"bytes,2,opt,name=current,proto3" json:"current,omitempty"`
+	Decisions     []*DecisionEvent       `protobuf:"bytes,3,rep,name=decisions,proto3" json:"decisions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecisionHistory) Reset() {
+	*x = DecisionHistory{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecisionHistory) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecisionHistory) ProtoMessage() {}
+
+func (x *DecisionHistory) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecisionHistory.ProtoReflect.Descriptor instead.
+func (*DecisionHistory) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DecisionHistory) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *DecisionHistory) GetCurrent() *DecisionEvent {
+	if x != nil {
+		return x.Current
+	}
+	return nil
+}
+
+func (x *DecisionHistory) GetDecisions() []*DecisionEvent {
+	if x != nil {
+		return x.Decisions
+	}
+	return nil
+}
+
+type GetDecisionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DecisionId    string                 `protobuf:"bytes,1,opt,name=decision_id,json=decisionId,proto3" json:"decision_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDecisionRequest) Reset() {
+	*x = GetDecisionRequest{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDecisionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDecisionRequest) ProtoMessage() {}
+
+func (x *GetDecisionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDecisionRequest.ProtoReflect.Descriptor instead.
+func (*GetDecisionRequest) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetDecisionRequest) GetDecisionId() string {
+	if x != nil {
+		return x.DecisionId
+	}
+	return ""
+}
+
+type GetDecisionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Decision      *DecisionEvent         `protobuf:"bytes,1,opt,name=decision,proto3" json:"decision,omitempty"`
+	History       *DecisionHistory       `protobuf:"bytes,2,opt,name=history,proto3" json:"history,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDecisionResponse) Reset() {
+	*x = GetDecisionResponse{}
+	mi := &file_riskr_v1_decision_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDecisionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDecisionResponse) ProtoMessage() {}
+
+func (x *GetDecisionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_riskr_v1_decision_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDecisionResponse.ProtoReflect.Descriptor instead.
+func (*GetDecisionResponse) Descriptor() ([]byte, []int) {
+	return file_riskr_v1_decision_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetDecisionResponse) GetDecision() *DecisionEvent {
+	if x != nil {
+		return x.Decision
+	}
+	return nil
+}
+
+func (x *GetDecisionResponse) GetHistory() *DecisionHistory {
+	if x != nil {
+		return x.History
+	}
+	return nil
+}
+
+var File_riskr_v1_decision_proto protoreflect.FileDescriptor
+
+const file_riskr_v1_decision_proto_rawDesc = "" +
+	"\n" +
+	"\x17riskr/v1/decision.proto\x12\briskr.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xbb\x01\n" +
+	"\aSubject\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x02 \x01(\tR\taccountId\x12\x1c\n" +
+	"\taddresses\x18\x03 \x03(\tR\taddresses\x12\x17\n" +
+	"\ageo_iso\x18\x04 \x01(\tR\x06geoIso\x12\x1b\n" +
+	"\tkyc_level\x18\x05 \x01(\tR\bkycLevel\x12\x12\n" +
+	"\x04name\x18\x06 \x01(\tR\x04name\x12\x10\n" +
+	"\x03dob\x18\a \x01(\tR\x03dob\"\x86\x01\n" +
+	"\x02Tx\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x14\n" +
+	"\x05asset\x18\x02 \x01(\tR\x05asset\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\tR\x06amount\x12\x1b\n" +
+	"\tusd_value\x18\x04 \x01(\x01R\busdValue\x12!\n" +
+	"\fdest_address\x18\x05 \x01(\tR\vdestAddress\"\xab\x01\n" +
+	"\fCheckRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12+\n" +
+	"\asubject\x18\x02 \x01(\v2\x11.riskr.v1.SubjectR\asubject\x12\x1c\n" +
+	"\x02tx\x18\x03 \x01(\v2\f.riskr.v1.TxR\x02tx\x121\n" +
+	"\acontext\x18\x04 \x01(\v2\x17.google.protobuf.StructR\acontext\"\xe4\x01\n" +
+	"\bEvidence\x12\x17\n" +
+	"\arule_id\x18\x01 \x01(\tR\x06ruleId\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12,\n" +
+	"\x05value\x18\x03 \x01(\v2\x16.google.protobuf.ValueR\x05value\x12,\n" +
+	"\x05limit\x18\x04 \x01(\v2\x16.google.protobuf.ValueR\x05limit\x12\x12\n" +
+	"\x04list\x18\x05 \x01(\tR\x04list\x12!\n" +
+	"\flist_version\x18\x06 \x01(\tR\vlistVersion\x12\x1a\n" +
+	"\bdegraded\x18\a \x01(\bR\bdegraded\"\xee\x02\n" +
+	"\rCheckResponse\x12\x1f\n" +
+	"\vdecision_id\x18\x01 \x01(\tR\n" +
+	"decisionId\x12\x19\n" +
+	"\bevent_id\x18\x02 \x01(\tR\aeventId\x12\x1a\n" +
+	"\bdecision\x18\x03 \x01(\tR\bdecision\x12#\n" +
+	"\rdecision_code\x18\x04 \x01(\tR\fdecisionCode\x12%\n" +
+	"\x0epolicy_version\x18\x05 \x01(\tR\rpolicyVersion\x12.\n" +
+	"\bevidence\x18\x06 \x03(\v2\x12.riskr.v1.EvidenceR\bevidence\x129\n" +
+	"\n" +
+	"expires_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\x12\x1a\n" +
+	"\breplayed\x18\b \x01(\bR\breplayed\x12\x16\n" +
+	"\x06cached\x18\t \x01(\bR\x06cached\x12\x1a\n" +
+	"\bdegraded\x18\n" +
+	" \x01(\bR\bdegraded\"A\n" +
+	"\x11CheckBatchRequest\x12,\n" +
+	"\x05items\x18\x01 \x03(\v2\x16.riskr.v1.CheckRequestR\x05items\"w\n" +
+	"\tBatchItem\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x121\n" +
+	"\x06result\x18\x02 \x01(\v2\x17.riskr.v1.CheckResponseH\x00R\x06result\x12\x16\n" +
+	"\x05error\x18\x03 \x01(\tH\x00R\x05errorB\t\n" +
+	"\aoutcome\"\x85\x01\n" +
+	"\x12CheckBatchResponse\x12\x19\n" +
+	"\bbatch_id\x18\x01 \x01(\tR\abatchId\x12%\n" +
+	"\x0epolicy_version\x18\x02 \x01(\tR\rpolicyVersion\x12-\n" +
+	"\aresults\x18\x03 \x03(\v2\x13.riskr.v1.BatchItemR\aresults\"\xaa\x03\n" +
+	"\rDecisionEvent\x12%\n" +
+	"\x0eschema_version\x18\x01 \x01(\tR\rschemaVersion\x12\x1f\n" +
+	"\vdecision_id\x18\x02 \x01(\tR\n" +
+	"decisionId\x12\x19\n" +
+	"\bevent_id\x18\x03 \x01(\tR\aeventId\x12\x17\n" +
+	"\auser_id\x18\x04 \x01(\tR\x06userId\x127\n" +
+	"\tissued_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\bissuedAt\x12\x14\n" +
+	"\x05stage\x18\x06 \x01(\tR\x05stage\x12\x1a\n" +
+	"\bdecision\x18\a \x01(\tR\bdecision\x12#\n" +
+	"\rdecision_code\x18\b \x01(\tR\fdecisionCode\x12%\n" +
+	"\x0epolicy_version\x18\t \x01(\tR\rpolicyVersion\x12.\n" +
+	"\bevidence\x18\n" +
+	" \x03(\v2\x12.riskr.v1.EvidenceR\bevidence\x12\x19\n" +
+	"\bbatch_id\x18\v \x01(\tR\abatchId\x12\x1b\n" +
+	"\tclient_id\x18\f \x01(\tR\bclientId\"\x96\x01\n" +
+	"\x0fDecisionHistory\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x121\n" +
+	"\acurrent\x18\x02 \x01(\v2\x17.riskr.v1.DecisionEventR\acurrent\x125\n" +
+	"\tdecisions\x18\x03 \x03(\v2\x17.riskr.v1.DecisionEventR\tdecisions\"5\n" +
+	"\x12GetDecisionRequest\x12\x1f\n" +
+	"\vdecision_id\x18\x01 \x01(\tR\n" +
+	"decisionId\"\x7f\n" +
+	"\x13GetDecisionResponse\x123\n" +
+	"\bdecision\x18\x01 \x01(\v2\x17.riskr.v1.DecisionEventR\bdecision\x123\n" +
+	"\ahistory\x18\x02 \x01(\v2\x19.riskr.v1.DecisionHistoryR\ahistory2\xe0\x01\n" +
+	"\x0fDecisionService\x128\n" +
+	"\x05Check\x12\x16.riskr.v1.CheckRequest\x1a\x17.riskr.v1.CheckResponse\x12G\n" +
+	"\n" +
+	"CheckBatch\x12\x1b.riskr.v1.CheckBatchRequest\x1a\x1c.riskr.v1.CheckBatchResponse\x12J\n" +
+	"\vGetDecision\x12\x1c.riskr.v1.GetDecisionRequest\x1a\x1d.riskr.v1.GetDecisionResponseB>Z<github.com/christophercampbell/riskr/pkg/api/riskrv1;riskrv1b\x06proto3"
+
+var (
+	file_riskr_v1_decision_proto_rawDescOnce sync.Once
+	file_riskr_v1_decision_proto_rawDescData []byte
+)
+
+func file_riskr_v1_decision_proto_rawDescGZIP() []byte {
+	file_riskr_v1_decision_proto_rawDescOnce.Do(func() {
+		file_riskr_v1_decision_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_riskr_v1_decision_proto_rawDesc), len(file_riskr_v1_decision_proto_rawDesc)))
+	})
+	return file_riskr_v1_decision_proto_rawDescData
+}
+
+var file_riskr_v1_decision_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_riskr_v1_decision_proto_goTypes = []any{
+	(*Subject)(nil),               // 0: riskr.v1.Subject
+	(*Tx)(nil),                    // 1: riskr.v1.Tx
+	(*CheckRequest)(nil),          // 2: riskr.v1.CheckRequest
+	(*Evidence)(nil),              // 3: riskr.v1.Evidence
+	(*CheckResponse)(nil),         // 4: riskr.v1.CheckResponse
+	(*CheckBatchRequest)(nil),     // 5: riskr.v1.CheckBatchRequest
+	(*BatchItem)(nil),             // 6: riskr.v1.BatchItem
+	(*CheckBatchResponse)(nil),    // 7: riskr.v1.CheckBatchResponse
+	(*DecisionEvent)(nil),         // 8: riskr.v1.DecisionEvent
+	(*DecisionHistory)(nil),       // 9: riskr.v1.DecisionHistory
+	(*GetDecisionRequest)(nil),    // 10: riskr.v1.GetDecisionRequest
+	(*GetDecisionResponse)(nil),   // 11: riskr.v1.GetDecisionResponse
+	(*structpb.Struct)(nil),       // 12: google.protobuf.Struct
+	(*structpb.Value)(nil),        // 13: google.protobuf.Value
+	(*timestamppb.Timestamp)(nil), // 14: google.protobuf.Timestamp
+}
+var file_riskr_v1_decision_proto_depIdxs = []int32{
+	0,  // 0: riskr.v1.CheckRequest.subject:type_name -> riskr.v1.Subject
+	1,  // 1: riskr.v1.CheckRequest.tx:type_name -> riskr.v1.Tx
+	12, // 2: riskr.v1.CheckRequest.context:type_name -> google.protobuf.Struct
+	13, // 3: riskr.v1.Evidence.value:type_name -> google.protobuf.Value
+	13, // 4: riskr.v1.Evidence.limit:type_name -> google.protobuf.Value
+	3,  // 5: riskr.v1.CheckResponse.evidence:type_name -> riskr.v1.Evidence
+	14, // 6: riskr.v1.CheckResponse.expires_at:type_name -> google.protobuf.Timestamp
+	2,  // 7: riskr.v1.CheckBatchRequest.items:type_name -> riskr.v1.CheckRequest
+	4,  // 8: riskr.v1.BatchItem.result:type_name -> riskr.v1.CheckResponse
+	6,  // 9: riskr.v1.CheckBatchResponse.results:type_name -> riskr.v1.BatchItem
+	14, // 10: riskr.v1.DecisionEvent.issued_at:type_name -> google.protobuf.Timestamp
+	3,  // 11: riskr.v1.DecisionEvent.evidence:type_name -> riskr.v1.Evidence
+	8,  // 12: riskr.v1.DecisionHistory.current:type_name -> riskr.v1.DecisionEvent
+	8,  // 13: riskr.v1.DecisionHistory.decisions:type_name -> riskr.v1.DecisionEvent
+	8,  // 14: riskr.v1.GetDecisionResponse.decision:type_name -> riskr.v1.DecisionEvent
+	9,  // 15: riskr.v1.GetDecisionResponse.history:type_name -> riskr.v1.DecisionHistory
+	2,  // 16: riskr.v1.DecisionService.Check:input_type -> riskr.v1.CheckRequest
+	5,  // 17: riskr.v1.DecisionService.CheckBatch:input_type -> riskr.v1.CheckBatchRequest
+	10, // 18: riskr.v1.DecisionService.GetDecision:input_type -> riskr.v1.GetDecisionRequest
+	4,  // 19: riskr.v1.DecisionService.Check:output_type -> riskr.v1.CheckResponse
+	7,  // 20: riskr.v1.DecisionService.CheckBatch:output_type -> riskr.v1.CheckBatchResponse
+	11, // 21: riskr.v1.DecisionService.GetDecision:output_type -> riskr.v1.GetDecisionResponse
+	19, // [19:22] is the sub-list for method output_type
+	16, // [16:19] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_riskr_v1_decision_proto_init() }
+func file_riskr_v1_decision_proto_init() {
+	if File_riskr_v1_decision_proto != nil {
+		return
+	}
+	file_riskr_v1_decision_proto_msgTypes[6].OneofWrappers = []any{
+		(*BatchItem_Result)(nil),
+		(*BatchItem_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_riskr_v1_decision_proto_rawDesc), len(file_riskr_v1_decision_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_riskr_v1_decision_proto_goTypes,
+		DependencyIndexes: file_riskr_v1_decision_proto_depIdxs,
+		MessageInfos:      file_riskr_v1_decision_proto_msgTypes,
+	}.Build()
+	File_riskr_v1_decision_proto = out.File
+	file_riskr_v1_decision_proto_goTypes = nil
+	file_riskr_v1_decision_proto_depIdxs = nil
+}
@@ -2,9 +2,12 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path"
+	"regexp"
 	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v3"
 )
@@ -14,8 +17,11 @@ type Config struct {
 	LogLevel        string             `yaml:"log_level" json:"log_level"`
 	NATS            NATS               `yaml:"nats" json:"nats"`
 	HTTP            HTTP               `yaml:"http" json:"http"`
+	Auth            Auth               `yaml:"auth" json:"auth"`
 	Policy          Policy             `yaml:"policy" json:"policy"`
 	Sanctions       Sanctions          `yaml:"sanctions" json:"sanctions"`
+	Webhooks        Webhooks           `yaml:"webhooks" json:"webhooks"`
+	Outbox          Outbox             `yaml:"outbox" json:"outbox"`
 	Assets          map[string]float64 `yaml:"assets" json:"assets"`
 	LatencyBudgetMS int                `yaml:"latency_budget_ms" json:"latency_budget_ms"`
 }
@@ -26,9 +32,23 @@ type NATS struct {
 }
 
 type HTTP struct {
-	ListenAddr     string `yaml:"listen_addr" json:"listen_addr"`
-	ReadTimeoutMS  int    `yaml:"read_timeout_ms" json:"read_timeout_ms"`
-	WriteTimeoutMS int    `yaml:"write_timeout_ms" json:"write_timeout_ms"`
+	ListenAddr       string `yaml:"listen_addr" json:"listen_addr"`
+	ReadTimeoutMS    int    `yaml:"read_timeout_ms" json:"read_timeout_ms"`
+	WriteTimeoutMS   int    `yaml:"write_timeout_ms" json:"write_timeout_ms"`
+	IdempotencyTTLMS int    `yaml:"idempotency_ttl_ms" json:"idempotency_ttl_ms"` // Idempotency-Key retention, default 10m
+	// TLS is enabled when a certificate is set; ClientCAFile enables client
+	// certificate verification for mTLS
+	TLSCertFile  string `yaml:"tls_cert_file" json:"tls_cert_file,omitempty"`
+	TLSKeyFile   string `yaml:"tls_key_file" json:"tls_key_file,omitempty"`
+	ClientCAFile string `yaml:"client_ca_file" json:"client_ca_file,omitempty"`
+}
+
+// Auth configures gateway API authentication. Methods (api_key, hmac, mtls)
+// are tried in order; with none, requests are not authenticated.
+type Auth struct {
+	Methods     []string `yaml:"methods" json:"methods"`
+	SecretsFile string   `yaml:"secrets_file" json:"secrets_file"` // client API keys and HMAC secrets, relative to the config file
+	MaxSkewMS   int      `yaml:"max_skew_ms" json:"max_skew_ms"`   // signed request timestamp tolerance, default 5m
 }
 
 type Policy struct {
@@ -39,6 +59,58 @@ type Sanctions struct {
 	File string `yaml:"file" json:"file"`
 }
 
+// Webhooks configures the webhook dispatcher. Backoff doubles per failed
+// attempt up to MaxBackoffMS; after MaxAttempts a decision is dead-lettered.
+type Webhooks struct {
+	MaxAttempts  int               `yaml:"max_attempts" json:"max_attempts"`
+	BackoffMS    int               `yaml:"backoff_ms" json:"backoff_ms"`
+	MaxBackoffMS int               `yaml:"max_backoff_ms" json:"max_backoff_ms"`
+	Endpoints    []WebhookEndpoint `yaml:"endpoints" json:"endpoints"`
+}
+
+// Outbox configures the gateway's on-disk buffer for events that cannot be
+// published while NATS is unavailable. When it holds MaxBytes, FullPolicy
+// decides: "refuse" fails the check (503), "degrade" answers with at least
+// FullDecision and marks the decision degraded and unrecorded. A check waits
+// at most PublishTimeoutMS for the broker before its records go to the outbox.
+type Outbox struct {
+	Dir              string `yaml:"dir" json:"dir"` // relative to the config file; empty disables buffering
+	MaxBytes         int64  `yaml:"max_bytes" json:"max_bytes"`
+	FullPolicy       string `yaml:"full_policy" json:"full_policy"`               // refuse|degrade, default refuse
+	FullDecision     string `yaml:"full_decision" json:"full_decision"`           // default HOLD_AUTO
+	PublishTimeoutMS int    `yaml:"publish_timeout_ms" json:"publish_timeout_ms"` // default 250
+}
+
+type WebhookEndpoint struct {
+	Name      string   `yaml:"name" json:"name"`
+	URL       string   `yaml:"url" json:"url"`
+	Secret    string   `yaml:"secret" json:"-"`                      // HMAC key; never serialized
+	Stages    []string `yaml:"stages" json:"stages,omitempty"`       // provisional|final|override, empty = final+override
+	Decisions []string `yaml:"decisions" json:"decisions,omitempty"` // empty = all
+	TimeoutMS int      `yaml:"timeout_ms" json:"timeout_ms"`
+}
+
+// webhookName is what an endpoint name may contain: it becomes part of a
+// consumer durable name and a DLQ subject token.
+var webhookName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (w Webhooks) validate() error {
+	seen := make(map[string]bool, len(w.Endpoints))
+	for _, ec := range w.Endpoints {
+		if !webhookName.MatchString(ec.Name) {
+			return fmt.Errorf("webhooks: endpoint name %q must be letters, digits, '-' or '_'", ec.Name)
+		}
+		if seen[ec.Name] {
+			return fmt.Errorf("webhooks: duplicate endpoint name %q", ec.Name)
+		}
+		seen[ec.Name] = true
+		if ec.URL == "" {
+			return fmt.Errorf("webhooks: endpoint %s needs a url", ec.Name)
+		}
+	}
+	return nil
+}
+
 func Load(filepath string) (*Config, error) {
 	b, err := os.ReadFile(filepath)
 	if err != nil {
@@ -49,6 +121,9 @@ func Load(filepath string) (*Config, error) {
 		return nil, err
 	}
 	applyEnvOverrides(&c)
+	if err := c.Webhooks.validate(); err != nil {
+		return nil, err
+	}
 
 	// set the configRoot for relative path parsing uses
 	c.configRoot = path.Dir(filepath)
@@ -70,31 +145,27 @@ func (c *Config) JSON() string {
 	return string(b)
 }
 
-func (c *Config) ResolvePolicyFile() string {
-	filepath := c.Policy.File
-	if !path.IsAbs(filepath) {
-		filepath = path.Join(c.configRoot, filepath)
+// DefaultIdempotencyTTL is the Idempotency-Key retention when unset.
+const DefaultIdempotencyTTL = 10 * time.Minute
+
+// IdempotencyTTL returns how long the gateway remembers idempotency keys. The
+// JetStream duplicate window must be at least as long, so a check retried
+// within it is not recorded twice.
+func (h HTTP) IdempotencyTTL() time.Duration {
+	if h.IdempotencyTTLMS <= 0 {
+		return DefaultIdempotencyTTL
 	}
-	return filepath
+	return time.Duration(h.IdempotencyTTLMS) * time.Millisecond
 }
 
-func (c *Config) ReadSanctions() (map[string]struct{}, error) {
-	filepath := c.Sanctions.File
+// ResolvePath resolves a relative path against the config file's directory.
+func (c *Config) ResolvePath(filepath string) string {
 	if !path.IsAbs(filepath) {
 		filepath = path.Join(c.configRoot, filepath)
 	}
-	b, err := os.ReadFile(filepath)
-	if err != nil {
-		return nil, err
-	}
-	m := make(map[string]struct{})
-	lines := strings.Split(string(b), "\n")
-	for _, ln := range lines {
-		ln = strings.TrimSpace(ln)
-		if ln == "" || strings.HasPrefix(ln, "#") {
-			continue
-		}
-		m[strings.ToLower(ln)] = struct{}{}
-	}
-	return m, nil
+	return filepath
+}
+
+func (c *Config) ResolvePolicyFile() string {
+	return c.ResolvePath(c.Policy.File)
 }
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadValidatesWebhookNames(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		endpoints string
+		ok        bool
+	}{
+		{"[{name: ledger, url: http://a}, {name: crm_2-b, url: http://b}]", true},
+		{"[{name: ledger.v2, url: http://a}]", false},
+		{"[{name: 'led ger', url: http://a}]", false},
+		{"[{name: 'led*', url: http://a}]", false},
+		{"[{name: '>', url: http://a}]", false},
+		{"[{name: '', url: http://a}]", false},
+		{"[{name: ledger, url: http://a}, {name: ledger, url: http://b}]", false},
+		{"[{name: ledger}]", false},
+	} {
+		path := filepath.Join(dir, "config.yaml")
+		if err := os.WriteFile(path, []byte("webhooks:\n  endpoints: "+tc.endpoints+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); (err == nil) != tc.ok {
+			t.Errorf("endpoints %s: err = %v", tc.endpoints, err)
+		}
+	}
+}
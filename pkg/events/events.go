@@ -10,6 +10,10 @@ import (
 // SchemaVersion increments when incompatible changes happen.
 const SchemaVersion = "v1"
 
+// ChainInline is the chain of events recording a gateway decision request
+// rather than an observed transfer.
+const ChainInline = "INLINE"
+
 // TxEvent represents an observed on-chain transfer relevant to a subject.
 type TxEvent struct {
 	SchemaVersion string    `json:"schema_version"`
@@ -19,12 +23,16 @@ type TxEvent struct {
 	Subject       Subject   `json:"subject"`
 	Chain         string    `json:"chain"`
 	TxHash        string    `json:"tx_hash"`
-	Direction     string    `json:"direction"` // inbound|outbound
+	Direction     string    `json:"direction"`              // inbound|outbound
+	Counterparty  string    `json:"counterparty,omitempty"` // address on the other side of the transfer
+	Address       string    `json:"address,omitempty"`      // subject's own address in the transfer (receiving inbound, sending outbound)
 	Asset         string    `json:"asset"`
 	Amount        string    `json:"amount"`    // base units decimal string
 	USDValue      string    `json:"usd_value"` // computed at obs time
 	Confirmations int       `json:"confirmations"`
 	MaxFinality   int       `json:"max_finality_depth"`
+	BatchID       string    `json:"batch_id,omitempty"`  // set for events of a batch decision request
+	ClientID      string    `json:"client_id,omitempty"` // authenticated API client that submitted the check
 }
 
 type Subject struct {
@@ -33,6 +41,9 @@ type Subject struct {
 	Addresses []string `json:"addresses"`
 	GeoISO    string   `json:"geo_iso"`
 	KYCTier   string   `json:"kyc_level"`
+	// optional identity attributes for name screening
+	Name string `json:"name,omitempty"`
+	DOB  string `json:"dob,omitempty"` // YYYY-MM-DD (a year alone is enough)
 }
 
 func (e *TxEvent) Marshal() ([]byte, error) { return json.Marshal(e) }
@@ -47,13 +58,16 @@ func (e *TxEvent) USDDecimal() decimal.Decimal {
 type DecisionEvent struct {
 	SchemaVersion string     `json:"schema_version"`
 	DecisionID    string     `json:"decision_id"`
-	EventID       string     `json:"event_id"` // correlates to TxEvent.EventID
+	EventID       string     `json:"event_id"`          // correlates to TxEvent.EventID
+	UserID        string     `json:"user_id,omitempty"` // subject of the tx event
 	IssuedAt      time.Time  `json:"issued_at"`
 	Stage         string     `json:"stage"` // provisional|final|override
 	Decision      string     `json:"decision"`
 	DecisionCode  string     `json:"decision_code"`
 	PolicyVersion string     `json:"policy_version"`
 	Evidence      []Evidence `json:"evidence"`
+	BatchID       string     `json:"batch_id,omitempty"`
+	ClientID      string     `json:"client_id,omitempty"` // API client of the check, for audit
 }
 
 type Evidence struct {
@@ -61,6 +75,12 @@ type Evidence struct {
 	Key    string      `json:"key"`
 	Value  interface{} `json:"value"`
 	Limit  interface{} `json:"limit,omitempty"`
+	// provenance for list-based hits
+	List        string `json:"list,omitempty"`
+	ListVersion string `json:"list_version,omitempty"`
+	// set when the rule was not evaluated (budget exceeded, dependency
+	// unavailable) and its fallback decision was used
+	Degraded bool `json:"degraded,omitempty"`
 }
 
 func (d *DecisionEvent) Marshal() ([]byte, error) { return json.Marshal(d) }
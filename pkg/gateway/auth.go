@@ -0,0 +1,319 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/christophercampbell/riskr/pkg/config"
+	"github.com/christophercampbell/riskr/pkg/log"
+)
+
+// Authentication headers. API keys may also be sent as "Authorization: Bearer <key>".
+const (
+	APIKeyHeader    = "X-API-Key"
+	ClientHeader    = "X-Riskr-Client"    // client ID of an HMAC-signed request
+	SignatureHeader = "X-Riskr-Signature" // t=<unix seconds>,v1=<hex hmac-sha256>
+)
+
+const (
+	defaultMaxSkew = 5 * time.Minute
+	authBodyLimit  = batchBodyLimit
+)
+
+var (
+	errUnauthenticated = errors.New("unauthenticated")
+	errNoCredentials   = errors.New("no credentials") // method not used by the request; try the next
+)
+
+// Secrets is the auth secrets file: per client, its API keys and HMAC secret.
+type Secrets struct {
+	Clients []ClientSecret `yaml:"clients"`
+}
+
+type ClientSecret struct {
+	ID         string   `yaml:"id"`
+	APIKeys    []string `yaml:"api_keys"`
+	HMACSecret string   `yaml:"hmac_secret"`
+}
+
+// authMethod identifies the client of r. It returns errNoCredentials when r
+// carries none of the method's credentials.
+type authMethod interface {
+	authenticate(r *http.Request) (string, error)
+}
+
+// authenticator is the gateway auth middleware: the first method for which a
+// request carries credentials decides. The client ID is put on the context.
+type authenticator struct {
+	methods []authMethod
+	log     log.Logger
+}
+
+func newAuthenticator(cfg *config.Config, logger log.Logger) (*authenticator, error) {
+	a := &authenticator{log: logger}
+	if len(cfg.Auth.Methods) == 0 {
+		logger.Warn("gateway API authentication disabled")
+		return a, nil
+	}
+	var sec Secrets
+	if cfg.Auth.SecretsFile != "" {
+		b, err := os.ReadFile(cfg.ResolvePath(cfg.Auth.SecretsFile))
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(b, &sec); err != nil {
+			return nil, fmt.Errorf("auth secrets: %w", err)
+		}
+	}
+	for _, m := range cfg.Auth.Methods {
+		switch m {
+		case "api_key":
+			a.methods = append(a.methods, newAPIKeyAuth(sec))
+		case "hmac":
+			skew := defaultMaxSkew
+			if cfg.Auth.MaxSkewMS > 0 {
+				skew = time.Duration(cfg.Auth.MaxSkewMS) * time.Millisecond
+			}
+			a.methods = append(a.methods, newHMACAuth(sec, skew))
+		case "mtls":
+			if cfg.HTTP.ClientCAFile == "" {
+				return nil, errors.New("auth method mtls needs http.client_ca_file")
+			}
+			a.methods = append(a.methods, newMTLSAuth(sec))
+		default:
+			return nil, fmt.Errorf("unknown auth method %q", m)
+		}
+	}
+	logger.Info("gateway API authentication", "methods", cfg.Auth.Methods, "clients", len(sec.Clients))
+	return a, nil
+}
+
+// wrap authenticates every request except /status and /metrics.
+func (a *authenticator) wrap(next http.Handler) http.Handler {
+	if len(a.methods) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/status" || r.URL.Path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, err := a.authenticate(r)
+		if err != nil {
+			a.log.Warn("unauthenticated request", "path", r.URL.Path, "remote", r.RemoteAddr, "err", err)
+			http.Error(w, errUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withClientID(r.Context(), id)))
+	})
+}
+
+// authenticateMsg identifies the client of a request/reply check. Messages
+// carry the HTTP credential headers: an API key, or a client ID and a
+// signature made with SignRequest with method "NATS" and the subject as URI.
+// Without auth methods every message is accepted with no client.
+func (a *authenticator) authenticateMsg(m *nats.Msg) (string, error) {
+	if len(a.methods) == 0 {
+		return "", nil
+	}
+	r := &http.Request{Method: "NATS", URL: &url.URL{Path: m.Subject}, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(m.Data))}
+	for k, vs := range m.Header {
+		for _, v := range vs {
+			r.Header.Add(k, v)
+		}
+	}
+	return a.authenticate(r)
+}
+
+func (a *authenticator) authenticate(r *http.Request) (string, error) {
+	for _, m := range a.methods {
+		id, err := m.authenticate(r)
+		if errors.Is(err, errNoCredentials) {
+			continue
+		}
+		return id, err
+	}
+	return "", errNoCredentials
+}
+
+type clientIDKey struct{}
+
+func withClientID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, clientIDKey{}, id)
+}
+
+// clientID returns the authenticated client of ctx, or "".
+func clientID(ctx context.Context) string {
+	id, _ := ctx.Value(clientIDKey{}).(string)
+	return id
+}
+
+// ------------------------ api keys ------------------------
+
+// apiKeyAuth looks keys up by their SHA-256, so keys are not kept in memory
+// and lookups do not leak timing about partial matches.
+type apiKeyAuth struct {
+	clients map[[sha256.Size]byte]string
+}
+
+func newAPIKeyAuth(sec Secrets) apiKeyAuth {
+	a := apiKeyAuth{clients: make(map[[sha256.Size]byte]string)}
+	for _, c := range sec.Clients {
+		for _, k := range c.APIKeys {
+			a.clients[sha256.Sum256([]byte(k))] = c.ID
+		}
+	}
+	return a
+}
+
+func (a apiKeyAuth) authenticate(r *http.Request) (string, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		key, _ = strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if key == "" {
+		return "", errNoCredentials
+	}
+	id, ok := a.clients[sha256.Sum256([]byte(key))]
+	if !ok {
+		return "", errors.New("unknown api key")
+	}
+	return id, nil
+}
+
+// ------------------------ hmac ------------------------
+
+// hmacAuth verifies signed requests. A signature is accepted once, and only
+// within maxSkew of its timestamp, so captured requests cannot be replayed.
+// gRPC requests are not signed this way (their body is a stream).
+type hmacAuth struct {
+	secrets map[string][]byte
+	maxSkew time.Duration
+
+	mu      sync.Mutex
+	seen    map[string]time.Time // signature -> expiry
+	sweepAt time.Time
+}
+
+func newHMACAuth(sec Secrets, maxSkew time.Duration) *hmacAuth {
+	a := &hmacAuth{secrets: make(map[string][]byte), maxSkew: maxSkew, seen: make(map[string]time.Time)}
+	for _, c := range sec.Clients {
+		if c.HMACSecret != "" {
+			a.secrets[c.ID] = []byte(c.HMACSecret)
+		}
+	}
+	return a
+}
+
+func (a *hmacAuth) authenticate(r *http.Request) (string, error) {
+	id, sig := r.Header.Get(ClientHeader), r.Header.Get(SignatureHeader)
+	if sig == "" || strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		return "", errNoCredentials
+	}
+	secret, ok := a.secrets[id]
+	if !ok {
+		return "", fmt.Errorf("unknown client %q", id)
+	}
+	var ts string
+	for _, part := range strings.Split(sig, ",") {
+		if v, ok := strings.CutPrefix(part, "t="); ok {
+			ts = v
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", errors.New("bad signature timestamp")
+	}
+	now := time.Now()
+	if d := now.Sub(time.Unix(sec, 0)); d > a.maxSkew || d < -a.maxSkew {
+		return "", errors.New("signature timestamp outside allowed skew")
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, authBodyLimit))
+	if err != nil {
+		return "", err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	want := SignRequest(secret, time.Unix(sec, 0), r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(sig), []byte(want)) {
+		return "", errors.New("bad signature")
+	}
+	if !a.once(sig, now, time.Unix(sec, 0).Add(a.maxSkew)) {
+		return "", errors.New("replayed signature")
+	}
+	return id, nil
+}
+
+// once records sig until it expires and reports whether it was unseen.
+func (a *hmacAuth) once(sig string, now, expires time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !now.Before(a.sweepAt) {
+		for k, exp := range a.seen {
+			if !now.Before(exp) {
+				delete(a.seen, k)
+			}
+		}
+		a.sweepAt = now.Add(a.maxSkew)
+	}
+	if exp, ok := a.seen[sig]; ok && now.Before(exp) {
+		return false
+	}
+	a.seen[sig] = expires
+	return true
+}
+
+// SignRequest returns the X-Riskr-Signature value for a request sent at t:
+// an HMAC-SHA256 over the timestamp, method, request URI and body.
+func SignRequest(secret []byte, t time.Time, method, uri string, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "." + method + "." + uri + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ------------------------ mtls ------------------------
+
+// mtlsAuth identifies clients by the common name of their verified
+// certificate, which must be a client ID in the secrets file.
+type mtlsAuth struct {
+	clients map[string]bool
+}
+
+func newMTLSAuth(sec Secrets) mtlsAuth {
+	a := mtlsAuth{clients: make(map[string]bool)}
+	for _, c := range sec.Clients {
+		a.clients[c.ID] = true
+	}
+	return a
+}
+
+func (a mtlsAuth) authenticate(r *http.Request) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return "", errNoCredentials
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if cn == "" {
+		return "", errors.New("client certificate has no common name")
+	}
+	if !a.clients[cn] {
+		return "", fmt.Errorf("unknown client %q", cn)
+	}
+	return cn, nil
+}
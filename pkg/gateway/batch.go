@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	maxBatchItems  = 1000
+	batchWorkers   = 16
+	batchBodyLimit = 8 << 20
+)
+
+// BatchResp returns one result per request item, in request order.
+type BatchResp struct {
+	BatchID       string      `json:"batch_id"`
+	PolicyVersion string      `json:"policy_version"`
+	Results       []BatchItem `json:"results"`
+}
+
+// BatchItem holds either the decision for an item or why it was not evaluated.
+type BatchItem struct {
+	Index  int           `json:"index"`
+	Result *DecisionResp `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+// handleBatch evaluates an array of DecisionReq concurrently against a single
+// policy snapshot. Malformed or invalid items fail individually; the events of
+// all items carry the batch ID.
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	start := time.Now()
+	var items []json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, batchBodyLimit)).Decode(&items); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(items) == 0 || len(items) > maxBatchItems {
+		http.Error(w, fmt.Sprintf("batch must have 1..%d items", maxBatchItems), http.StatusBadRequest)
+		return
+	}
+
+	in := make([]batchInput, len(items))
+	for i, raw := range items {
+		var req DecisionReq
+		if err := json.Unmarshal(raw, &req); err != nil {
+			in[i].err = err
+			continue
+		}
+		in[i].req = &req
+	}
+	resp := s.checkBatch(r.Context(), in)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+	s.log.Info("batch decision", "batch", resp.BatchID, "items", len(items), "ms", time.Since(start).Milliseconds())
+}
+
+// batchInput is one batch item: a decoded request, or why it could not be decoded.
+type batchInput struct {
+	req *DecisionReq
+	err error
+}
+
+// checkBatch evaluates in concurrently against a single policy snapshot,
+// returning results in input order under a new batch ID.
+func (s *Server) checkBatch(ctx context.Context, in []batchInput) BatchResp {
+	snap := s.active.Load()
+	resp := BatchResp{BatchID: randID(), PolicyVersion: snap.Version, Results: make([]BatchItem, len(in))}
+
+	idx := make(chan int)
+	var wg sync.WaitGroup
+	for range min(batchWorkers, len(in)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idx {
+				res := BatchItem{Index: i}
+				if in[i].err != nil {
+					res.Error = in[i].err.Error()
+				} else if dr, err := s.evaluate(ctx, snap, in[i].req, resp.BatchID); err != nil {
+					res.Error = err.Error()
+				} else {
+					res.Result = &dr
+				}
+				resp.Results[i] = res
+			}
+		}()
+	}
+	for i := range in {
+		idx <- i
+	}
+	close(idx)
+	wg.Wait()
+	return resp
+}
@@ -0,0 +1,175 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/decision"
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/outbox"
+	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+)
+
+// newBatchServer holds checks from KP and allows the rest.
+func newBatchServer(t *testing.T) *Server {
+	t.Helper()
+	p := &policy.Policy{
+		Version: "p0",
+		Rules:   []policy.RuleDef{{ID: "R2_JURIS", Type: "jurisdiction_block", Action: decision.HoldAuto, BlockedCountries: []string{"KP"}}},
+		Lists:   []policy.ListDef{{Name: "L", Source: writeList(t, t.TempDir(), "a")}},
+	}
+	reg := sanctions.NewRegistry(func(s string) string { return s }, nil)
+	if _, err := reg.Apply(p.Lists); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer()
+	if err := s.active.Follow(p, reg, func(err error) { t.Error(err) }); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func postBatch(s *Server, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.handleBatch(w, httptest.NewRequest("POST", "/v1/decision/batch", strings.NewReader(body)))
+	return w
+}
+
+func batchBody(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		geo := "US"
+		if i%2 == 1 {
+			geo = "KP"
+		}
+		items[i] = fmt.Sprintf(`{"subject":{"user_id":"u%d","geo_iso":%q},"tx":{"type":"withdraw","usd_value":%d}}`, i, geo, i)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+func TestBatchSizeLimit(t *testing.T) {
+	s := newBatchServer(t)
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{"[]", http.StatusBadRequest},
+		{batchBody(maxBatchItems + 1), http.StatusBadRequest},
+		{batchBody(1), http.StatusOK},
+		{batchBody(maxBatchItems), http.StatusOK},
+		{"{}", http.StatusBadRequest},
+	} {
+		w := postBatch(s, tc.body)
+		if w.Code != tc.want {
+			t.Errorf("%d bytes: status %d, want %d: %s", len(tc.body), w.Code, tc.want, w.Body.String())
+		}
+	}
+}
+
+func TestBatchItemErrors(t *testing.T) {
+	s := newBatchServer(t)
+	body := `[
+		{"subject":{"user_id":"u0","geo_iso":"US"},"tx":{"usd_value":1}},
+		{"subject":"not an object"},
+		{"subject":{"geo_iso":"US"},"tx":{"usd_value":1}},
+		{"subject":{"user_id":"u3"},"tx":{"usd_value":-5}},
+		{"subject":{"user_id":"u4","geo_iso":"KP"},"tx":{"usd_value":1}}
+	]`
+	w := postBatch(s, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var resp BatchResp
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		decision string // empty: the item fails with an error containing err
+		err      string
+	}{
+		{decision.Allow, ""},
+		{"", "cannot unmarshal"},
+		{"", "user_id is required"},
+		{"", "must not be negative"},
+		{decision.HoldAuto, ""},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("%d results, want %d", len(resp.Results), len(want))
+	}
+	for i, res := range resp.Results {
+		switch {
+		case res.Index != i:
+			t.Errorf("result %d has index %d", i, res.Index)
+		case want[i].decision != "" && (res.Result == nil || res.Result.Decision != want[i].decision || res.Error != ""):
+			t.Errorf("item %d: %+v, want %s", i, res, want[i].decision)
+		case want[i].decision == "" && (res.Result != nil || !strings.Contains(res.Error, want[i].err)):
+			t.Errorf("item %d: %+v, want error %q", i, res, want[i].err)
+		}
+	}
+}
+
+// Items complete out of order on the worker pool; results stay in request
+// order, at most batchWorkers items are in flight, and every event carries
+// the batch ID.
+func TestBatchOrderUnderWorkerPool(t *testing.T) {
+	s := newBatchServer(t)
+	var inFlight, peak atomic.Int32
+	var batchIDs sync.Map
+	s.pub = func(_ context.Context, recs []outbox.Record) (int, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Duration(rand.IntN(500)) * time.Microsecond)
+		var te events.TxEvent
+		if err := te.Unmarshal(recs[0].Data); err != nil {
+			return 0, err
+		}
+		batchIDs.Store(te.BatchID, true)
+		return len(recs), nil
+	}
+
+	const n = 200
+	in := make([]batchInput, n)
+	for i := range in {
+		geo := "US"
+		if i%3 == 0 {
+			geo = "KP"
+		}
+		in[i].req = &DecisionReq{Subject: events.Subject{UserID: fmt.Sprint("u", i), GeoISO: geo}}
+	}
+	resp := s.checkBatch(context.Background(), in)
+	for i, res := range resp.Results {
+		want := decision.Allow
+		if i%3 == 0 {
+			want = decision.HoldAuto
+		}
+		if res.Index != i || res.Result == nil || res.Result.Decision != want {
+			t.Fatalf("result %d: %+v, want index %d %s", i, res, i, want)
+		}
+	}
+	if p := peak.Load(); p > batchWorkers || p < 2 {
+		t.Errorf("peak in flight = %d, want 2..%d", p, batchWorkers)
+	}
+	ids := 0
+	batchIDs.Range(func(k, _ any) bool {
+		ids++
+		if k != resp.BatchID {
+			t.Errorf("event batch id %v, want %s", k, resp.BatchID)
+		}
+		return true
+	})
+	if ids != 1 {
+		t.Errorf("%d batch ids on events, want 1", ids)
+	}
+}
@@ -0,0 +1,62 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/decision"
+	"github.com/christophercampbell/riskr/pkg/rules"
+)
+
+// decisionCache serves repeated identical checks (same client, request body
+// and request ID, same policy and list versions) with the original decision
+// until it expires. A hit publishes nothing, so only HOLD_AUTO and
+// SOFT_DENY_RETRY decisions are cached: they move no funds, and the streamer
+// has already seen the original tx. Degraded decisions are not cached.
+type decisionCache struct {
+	mu      sync.Mutex
+	entries map[string]DecisionResp
+	sweepAt time.Time
+}
+
+const cacheSweepEvery = time.Minute
+
+func newDecisionCache() *decisionCache {
+	return &decisionCache{entries: make(map[string]DecisionResp)}
+}
+
+// cacheKey identifies a check to the cache: its snapshot's policy and list
+// versions, client, request ID and request fingerprint.
+func cacheKey(snap *rules.Snapshot, req *DecisionReq, fp string) string {
+	return snap.Version + "|" + snap.ListVersions() + "|" + req.ClientID + "|" + req.RequestID + "|" + fp
+}
+
+func (c *decisionCache) get(key string, now time.Time) (DecisionResp, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.entries[key]
+	if !ok || !now.Before(*r.ExpiresAt) {
+		return DecisionResp{}, false
+	}
+	return r, true
+}
+
+func (c *decisionCache) put(key string, r DecisionResp, now time.Time) {
+	if r.ExpiresAt == nil || r.Replayed || r.Degraded {
+		return
+	}
+	if r.Decision != decision.HoldAuto && r.Decision != decision.SoftDeny {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !now.Before(c.sweepAt) {
+		for k, e := range c.entries {
+			if !now.Before(*e.ExpiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		c.sweepAt = now.Add(cacheSweepEvery)
+	}
+	c.entries[key] = r
+}
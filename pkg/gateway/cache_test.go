@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/decision"
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+)
+
+func TestDecisionCacheCachesOnlyExpiringHolds(t *testing.T) {
+	now := time.Now()
+	exp := now.Add(time.Minute)
+	for _, tc := range []struct {
+		name string
+		resp DecisionResp
+		want bool
+	}{
+		{"hold", DecisionResp{Decision: decision.HoldAuto, ExpiresAt: &exp}, true},
+		{"soft deny", DecisionResp{Decision: decision.SoftDeny, ExpiresAt: &exp}, true},
+		{"hold without ttl", DecisionResp{Decision: decision.HoldAuto}, false},
+		{"allow", DecisionResp{Decision: decision.Allow, ExpiresAt: &exp}, false},
+		{"review", DecisionResp{Decision: decision.Review, ExpiresAt: &exp}, false},
+		{"reject", DecisionResp{Decision: decision.RejectFatal, ExpiresAt: &exp}, false},
+		{"degraded", DecisionResp{Decision: decision.HoldAuto, ExpiresAt: &exp, Degraded: true}, false},
+		{"replayed", DecisionResp{Decision: decision.HoldAuto, ExpiresAt: &exp, Replayed: true}, false},
+	} {
+		c := newDecisionCache()
+		c.put("k", tc.resp, now)
+		if _, ok := c.get("k", now); ok != tc.want {
+			t.Errorf("%s: cached = %v, want %v", tc.name, ok, tc.want)
+		}
+	}
+}
+
+func TestDecisionCacheExpiry(t *testing.T) {
+	c := newDecisionCache()
+	now := time.Now()
+	exp := now.Add(time.Minute)
+	c.put("k", DecisionResp{DecisionID: "d1", Decision: decision.HoldAuto, ExpiresAt: &exp}, now)
+	if r, ok := c.get("k", exp.Add(-time.Nanosecond)); !ok || r.DecisionID != "d1" {
+		t.Fatalf("before expiry: %+v %v", r, ok)
+	}
+	if _, ok := c.get("k", exp); ok {
+		t.Fatal("served at expiry")
+	}
+	// expired entries are swept by a later put
+	later := exp.Add(cacheSweepEvery)
+	exp2 := later.Add(time.Minute)
+	c.put("k2", DecisionResp{Decision: decision.HoldAuto, ExpiresAt: &exp2}, later)
+	if _, ok := c.entries["k"]; ok || len(c.entries) != 1 {
+		t.Fatalf("entries after sweep = %v", c.entries)
+	}
+}
+
+// An identical check is served from the cache; a check differing in client,
+// request ID or body, or evaluated after a policy or list swap, is not.
+func TestEvaluateCache(t *testing.T) {
+	dir := t.TempDir()
+	p := &policy.Policy{
+		Version: "p0",
+		Rules:   []policy.RuleDef{{ID: "R2_JURIS", Type: "jurisdiction_block", Action: decision.HoldAuto, BlockedCountries: []string{"KP"}, TTL: "10m"}},
+		Lists:   []policy.ListDef{{Name: "L", Source: writeList(t, dir, "a")}},
+	}
+	reg := sanctions.NewRegistry(func(s string) string { return s }, nil)
+	if _, err := reg.Apply(p.Lists); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer()
+	if err := s.active.Follow(p, reg, func(err error) { t.Error(err) }); err != nil {
+		t.Fatal(err)
+	}
+	check := func(client, requestID string, usd float64) DecisionResp {
+		t.Helper()
+		req := &DecisionReq{RequestID: requestID, Subject: events.Subject{UserID: "u1", GeoISO: "KP"}}
+		req.Tx.Type, req.Tx.USDValue = "withdraw", usd
+		resp, err := s.evaluate(withClientID(context.Background(), client), s.active.Load(), req, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Decision != decision.HoldAuto || resp.ExpiresAt == nil {
+			t.Fatalf("decision = %s expires %v, want an expiring hold", resp.Decision, resp.ExpiresAt)
+		}
+		return resp
+	}
+
+	first := check("a", "", 100)
+	if first.Cached {
+		t.Fatal("first check cached")
+	}
+	if r := check("a", "", 100); !r.Cached || r.DecisionID != first.DecisionID {
+		t.Fatalf("repeat: cached=%v decision %s, want %s", r.Cached, r.DecisionID, first.DecisionID)
+	}
+	for name, miss := range map[string]func() DecisionResp{
+		"other client":     func() DecisionResp { return check("b", "", 100) },
+		"other request id": func() DecisionResp { return check("a", "r1", 100) },
+		"other body":       func() DecisionResp { return check("a", "", 101) },
+	} {
+		if r := miss(); r.Cached {
+			t.Errorf("%s: served from cache", name)
+		}
+	}
+
+	np := *p
+	np.Version = "p1"
+	if err := s.active.Apply(&np, reg); err != nil {
+		t.Fatal(err)
+	}
+	if r := check("a", "", 100); r.Cached {
+		t.Fatal("served from cache after a policy swap")
+	}
+	l, err := sanctions.LoadFile("L", writeList(t, dir, "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reg.Swap(l); err != nil {
+		t.Fatal(err)
+	}
+	if v := s.active.Load().ListVersions(); v != "L@b," {
+		t.Fatalf("lists = %s after swap", v)
+	}
+	if r := check("a", "", 100); r.Cached {
+		t.Fatal("served from cache after a list swap")
+	}
+	if r := check("a", "", 100); !r.Cached {
+		t.Fatal("repeat after swaps not cached")
+	}
+}
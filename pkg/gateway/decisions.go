@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/log"
+	"github.com/christophercampbell/riskr/pkg/natsjs"
+)
+
+// maxViewEvents bounds the decision view; the oldest events are evicted first.
+const maxViewEvents = 100_000
+
+// viewReplay is how many of the newest stream messages the view replays at
+// start: enough for a provisional and a final decision per event it holds.
+const viewReplay = 2 * maxViewEvents
+
+// decisionView is an in-memory materialized view of the DECISIONS stream:
+// every decision (provisional, final, override) grouped by tx event.
+type decisionView struct {
+	mu      sync.RWMutex
+	byEvent map[string][]events.DecisionEvent
+	eventOf map[string]string // decision id -> event id
+	order   []string          // event ids, oldest first, for eviction
+}
+
+func newDecisionView() *decisionView {
+	return &decisionView{byEvent: make(map[string][]events.DecisionEvent), eventOf: make(map[string]string)}
+}
+
+// follow replays the tail of the DECISIONS stream into the view and keeps it
+// current until ctx ends.
+func (v *decisionView) follow(ctx context.Context, js nats.JetStreamContext, logger log.Logger) error {
+	info, err := js.StreamInfo(natsjs.StreamDecisions)
+	if err != nil {
+		return err
+	}
+	start := nats.DeliverAll()
+	if last := info.State.LastSeq; last > viewReplay {
+		start = nats.StartSequence(last - viewReplay + 1)
+	}
+	sub, err := js.Subscribe(natsjs.SubjDecisionsAll, func(m *nats.Msg) {
+		var de events.DecisionEvent
+		if err := de.Unmarshal(m.Data); err != nil {
+			logger.Error("decision view unmarshal", "subject", m.Subject, "err", err)
+			return
+		}
+		v.add(de)
+	}, nats.BindStream(natsjs.StreamDecisions), nats.OrderedConsumer(), start)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = sub.Unsubscribe()
+	}()
+	return nil
+}
+
+// add records de once; the gateway adds its own decisions directly so they are
+// readable before the stream delivers them back.
+func (v *decisionView) add(de events.DecisionEvent) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, seen := v.eventOf[de.DecisionID]; seen {
+		return
+	}
+	if _, ok := v.byEvent[de.EventID]; !ok {
+		v.order = append(v.order, de.EventID)
+		if len(v.order) > maxViewEvents {
+			v.evictLocked(v.order[0])
+			v.order = v.order[1:]
+		}
+	}
+	v.byEvent[de.EventID] = append(v.byEvent[de.EventID], de)
+	v.eventOf[de.DecisionID] = de.EventID
+}
+
+func (v *decisionView) evictLocked(eventID string) {
+	for _, de := range v.byEvent[eventID] {
+		delete(v.eventOf, de.DecisionID)
+	}
+	delete(v.byEvent, eventID)
+}
+
+// history returns the decisions for eventID in the order they were issued.
+// With client set, only events that client submitted are found.
+func (v *decisionView) history(eventID, client string) (DecisionHistory, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	ds, ok := v.byEvent[eventID]
+	if !ok || !visible(ds[0], client) {
+		return DecisionHistory{}, false
+	}
+	h := DecisionHistory{EventID: eventID, Decisions: append([]events.DecisionEvent(nil), ds...)}
+	h.Current = h.Decisions[len(h.Decisions)-1]
+	return h, true
+}
+
+// lookup returns the decision with the given id and the history of its event,
+// scoped to client like history.
+func (v *decisionView) lookup(decisionID, client string) (DecisionLookup, bool) {
+	v.mu.RLock()
+	eventID, ok := v.eventOf[decisionID]
+	v.mu.RUnlock()
+	if !ok {
+		return DecisionLookup{}, false
+	}
+	h, ok := v.history(eventID, client)
+	if !ok {
+		return DecisionLookup{}, false
+	}
+	for _, de := range h.Decisions {
+		if de.DecisionID == decisionID {
+			return DecisionLookup{Decision: de, History: h}, true
+		}
+	}
+	return DecisionLookup{}, false
+}
+
+// visible reports whether client may read de: decisions are readable by the
+// client whose check (or on-chain event) they belong to. An empty client
+// (authentication disabled) reads everything.
+func visible(de events.DecisionEvent, client string) bool {
+	return client == "" || de.ClientID == client
+}
+
+// DecisionHistory is the decision lifecycle of one tx event. Current is the
+// latest decision, which supersedes the earlier ones.
+type DecisionHistory struct {
+	EventID   string                 `json:"event_id"`
+	Current   events.DecisionEvent   `json:"current"`
+	Decisions []events.DecisionEvent `json:"decisions"`
+}
+
+type DecisionLookup struct {
+	Decision events.DecisionEvent `json:"decision"`
+	History  DecisionHistory      `json:"history"`
+}
+
+func (s *Server) handleGetDecision(w http.ResponseWriter, r *http.Request) {
+	res, ok := s.decisions.lookup(r.PathValue("id"), clientID(r.Context()))
+	if !ok {
+		http.Error(w, "decision not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (s *Server) handleEventDecisions(w http.ResponseWriter, r *http.Request) {
+	res, ok := s.decisions.history(r.PathValue("event_id"), clientID(r.Context()))
+	if !ok {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
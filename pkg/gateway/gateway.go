@@ -5,6 +5,8 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/nats-io/nats.go"
 	"net/http"
 	"time"
@@ -16,38 +18,105 @@ import (
 	"github.com/christophercampbell/riskr/pkg/events"
 	"github.com/christophercampbell/riskr/pkg/log"
 	"github.com/christophercampbell/riskr/pkg/natsjs"
+	"github.com/christophercampbell/riskr/pkg/outbox"
 	"github.com/christophercampbell/riskr/pkg/policy"
 	"github.com/christophercampbell/riskr/pkg/rules"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+	"github.com/christophercampbell/riskr/pkg/state"
 )
 
 type Server struct {
-	cfg           *config.Config
-	log           log.Logger
-	nc            *nats.Conn
-	rules         []rules.Rule
-	policyVersion string
+	cfg       *config.Config
+	log       log.Logger
+	nc        *nats.Conn
+	js        nats.JetStreamContext
+	lists     *sanctions.Registry
+	active    rules.Active // policy, lists and rules; replaced whole on reload
+	idem      *idemCache
+	cache     *decisionCache
+	decisions *decisionView
+	out       *outbox.Outbox // nil when buffering is disabled
+	auth      *authenticator
+	pub       outbox.PublishFunc // publishRecords; replaced in tests
+	state     state.View         // destinations this instance checked, for the inline new destination rule
+	metrics   metrics
 }
 
 func Run(ctx context.Context, cfg *config.Config, logger log.Logger) error {
-	// connect nats
-	nc, err := natsjs.Connect(ctx, cfg.NATS.URLs, nats.Name("riskr-policy-run"))
+	// connect nats; with no reconnect buffer, publishes while disconnected
+	// fail instead of queueing in memory, and go to the outbox
+	nc, err := natsjs.Connect(ctx, cfg.NATS.URLs, nats.Name("riskr-policy-run"), nats.ReconnectBufSize(-1))
 	if err != nil {
 		return err
 	}
 
-	// load sanctions
-	sanctions, err := cfg.ReadSanctions()
+	// load policy
+	p, err := policy.LoadFile(cfg.ResolvePolicyFile())
 	if err != nil {
 		return err
 	}
 
-	// load policy
-	p, err := policy.LoadFile(cfg.ResolvePolicyFile())
+	// load screening lists; hot-reload versions published to the object store
+	js, err := natsjs.JetStream(nc)
+	if err != nil {
+		return err
+	}
+	if cfg.NATS.EnsureStreams {
+		if err = natsjs.Bootstrap(js, cfg.HTTP.IdempotencyTTL()); err != nil {
+			return err
+		}
+	}
+	reg, err := sanctions.Open(ctx, cfg, p, js, logger)
 	if err != nil {
 		return err
 	}
 
-	s := &Server{cfg: cfg, log: logger, nc: nc, rules: rules.BuildRules(p, sanctions, p.Params), policyVersion: p.Version}
+	auth, err := newAuthenticator(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	// idempotency keys are shared by the instances, so a retry routed to any
+	// of them gets the original decision
+	idemKV, err := natsjs.KeyValue(js, natsjs.BucketIdempotency, cfg.HTTP.IdempotencyTTL())
+	if err != nil {
+		return err
+	}
+
+	s := &Server{cfg: cfg, log: logger, nc: nc, js: js, lists: reg, idem: newIdemCache(cfg.HTTP.IdempotencyTTL(), kvIdemStore{idemKV}, logger), cache: newDecisionCache(), decisions: newDecisionView(), auth: auth, state: state.NewMem()}
+	s.pub = s.publishRecords
+	// a published list version makes a new snapshot with the current policy
+	err = s.active.Follow(p, reg, func(err error) {
+		logger.Error("policy recompile on list update", "err", err)
+	})
+	if err != nil {
+		return fmt.Errorf("policy %s: %w", p.Version, err)
+	}
+
+	if cfg.Outbox.Dir != "" {
+		dir := cfg.ResolvePath(cfg.Outbox.Dir)
+		if s.out, err = outbox.Open(dir, cfg.Outbox.MaxBytes, s.pub, logger); err != nil {
+			return err
+		}
+		go s.out.Run(ctx)
+		logger.Info("outbox open", "dir", dir, "max_bytes", cfg.Outbox.MaxBytes, "full_policy", cfg.Outbox.FullPolicy)
+	} else {
+		logger.Warn("outbox disabled; events published while NATS is unavailable are lost")
+	}
+	nc.SetDisconnectErrHandler(func(_ *nats.Conn, err error) {
+		logger.Warn("nats disconnected", "err", err)
+	})
+	nc.SetReconnectHandler(func(_ *nats.Conn) {
+		logger.Info("nats reconnected")
+		if s.out != nil {
+			s.out.Kick()
+		}
+	})
+
+	// decision lookups are served from a view of the DECISIONS stream
+	if err = s.decisions.follow(ctx, js, logger); err != nil {
+		return err
+	}
 
 	_, err = natsjs.SubscribeEphemeral(ctx, nc, natsjs.SubjPolicyBroadcast, func(m *nats.Msg) {
 		var np policy.Policy
@@ -56,36 +125,50 @@ func Run(ctx context.Context, cfg *config.Config, logger log.Logger) error {
 			return
 		}
 		logger.Info("policy update", "ver", np.Version)
-		s.rules = rules.BuildRules(&np, sanctions, np.Params)
-		s.policyVersion = np.Version
+		if err := s.active.Apply(&np, reg); err != nil {
+			logger.Error("policy lists", "ver", np.Version, "err", err)
+		}
 	})
 	if err != nil {
 		return err
 	}
 
+	// request/reply decisions for NATS clients, load-shared across instances
+	logger.Info("subscribing", "subject", natsjs.SubjDecisionCheck, "queue", checkQueue, "workers", checkWorkers)
+	if err = s.serveChecks(ctx); err != nil {
+		return err
+	}
+
 	return serveHTTP(ctx, cfg, logger, s)
 }
 
 // Request types for inline decision
 
 type DecisionReq struct {
-	Subject events.Subject `json:"subject"`
-	Tx      struct {
+	RequestID string         `json:"request_id,omitempty"` // idempotency key; the Idempotency-Key header takes precedence
+	Subject   events.Subject `json:"subject"`
+	Tx        struct {
 		Type        string  `json:"type"` // withdraw|deposit
 		Asset       string  `json:"asset"`
 		Amount      string  `json:"amount"` // base units string (unused for now)
 		USDValue    float64 `json:"usd_value"`
 		DestAddress string  `json:"dest_address"`
 	} `json:"tx"`
-	Context map[string]any `json:"context"`
+	Context  map[string]any `json:"context"`
+	ClientID string         `json:"-"` // authenticated API client, set from the request context
 }
 
 type DecisionResp struct {
+	DecisionID    string            `json:"decision_id"`
+	EventID       string            `json:"event_id"`
 	Decision      string            `json:"decision"`
 	DecisionCode  string            `json:"decision_code"`
 	PolicyVersion string            `json:"policy_version"`
 	Evidence      []events.Evidence `json:"evidence"`
 	ExpiresAt     *time.Time        `json:"expires_at,omitempty"`
+	Replayed      bool              `json:"replayed,omitempty"` // original decision returned for a retried idempotency key
+	Cached        bool              `json:"cached,omitempty"`   // unexpired decision returned for an identical check
+	Degraded      bool              `json:"degraded,omitempty"` // some rules used their fallback decision, see evidence
 }
 
 func (s *Server) handleDecision(w http.ResponseWriter, r *http.Request) {
@@ -95,69 +178,286 @@ func (s *Server) handleDecision(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if k := r.Header.Get(IdempotencyHeader); k != "" {
+		req.RequestID = k
+	}
+	resp, err := s.evaluate(r.Context(), s.active.Load(), &req, "")
+	if err != nil {
+		http.Error(w, err.Error(), httpStatus(err))
+		return
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+	dur := time.Since(start)
+	if dur > time.Duration(s.cfg.LatencyBudgetMS)*time.Millisecond {
+		s.log.Warn("decision latency over budget", "ms", dur.Milliseconds())
+	}
+}
+
+var (
+	errInvalidRequest = errors.New("invalid request")
+	errNotRecorded    = errors.New("decision could not be recorded")
+)
+
+// validate rejects requests that cannot be evaluated meaningfully.
+func (req *DecisionReq) validate() error {
+	if req.Subject.UserID == "" {
+		return fmt.Errorf("%w: subject.user_id is required", errInvalidRequest)
+	}
+	if req.Tx.USDValue < 0 {
+		return fmt.Errorf("%w: tx.usd_value must not be negative", errInvalidRequest)
+	}
+	return nil
+}
+
+// httpStatus maps an evaluate error to an HTTP status code.
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, errIdempotencyMismatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled), errors.Is(err, errNotRecorded):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+// evaluate checks req against snap, or returns the original decision when
+// req carries an idempotency key that was already seen. It is shared by the
+// HTTP, NATS and gRPC APIs.
+func (s *Server) evaluate(ctx context.Context, snap *rules.Snapshot, req *DecisionReq, batchID string) (DecisionResp, error) {
+	if err := req.validate(); err != nil {
+		return DecisionResp{}, err
+	}
+	req.ClientID = clientID(ctx)
+	// a blocking decision with a TTL holds for the same client's identical
+	// checks until it expires
+	fp := fingerprint(req)
+	ck := cacheKey(snap, req, fp)
+	if resp, ok := s.cache.get(ck, time.Now()); ok {
+		s.log.Info("cached decision", "decision_id", resp.DecisionID, "expires_at", resp.ExpiresAt)
+		resp.Cached = true
+		return resp, nil
+	}
+	if req.RequestID == "" {
+		resp, err := s.decide(ctx, snap, req, batchID)
+		if err == nil {
+			s.cache.put(ck, resp, time.Now())
+		}
+		return resp, err
+	}
+	key := idemKey(req)
+	resp, replayed, err := s.idem.do(ctx, key, fp, func() (DecisionResp, error) {
+		return s.decide(ctx, snap, req, batchID)
+	})
+	if replayed {
+		s.log.Info("idempotent replay", "key", key)
+		resp.Replayed = true
+	}
+	if err == nil {
+		s.cache.put(ck, resp, time.Now())
+	}
+	return resp, err
+}
+
+// decide runs the inline rules of snap for req and publishes the synthetic
+// tx event and provisional decision for the streamer. If ctx ends first the
+// caller has given up and nothing is published.
+func (s *Server) decide(ctx context.Context, snap *rules.Snapshot, req *DecisionReq, batchID string) (DecisionResp, error) {
+	eventID, decisionID := randID(), randID()
+	if req.RequestID != "" {
+		key := idemKey(req)
+		eventID, decisionID = stableID(key, "event"), stableID(key, "provisional")
+	}
 
-	s.log.Info("handling decision request", "subject", req.Subject, "tx_type", req.Tx.Type)
+	s.log.Info("handling decision request", "user_id", req.Subject.UserID, "tx_type", req.Tx.Type, "batch", batchID)
 
 	// Build synthetic TxEvent for rule eval
 	usd := decimal.NewFromFloat(req.Tx.USDValue)
 	te := &events.TxEvent{
 		SchemaVersion: events.SchemaVersion,
-		EventID:       randID(),
+		EventID:       eventID,
 		OccurredAt:    time.Now(),
 		ObservedAt:    time.Now(),
 		Subject:       req.Subject,
-		Chain:         "INLINE", // not chain-specific, request-level
+		Chain:         events.ChainInline, // not chain-specific, request-level
 		TxHash:        "",
 		Direction:     "outbound",
+		Counterparty:  req.Tx.DestAddress,
 		Asset:         req.Tx.Asset,
 		Amount:        req.Tx.Amount,
 		USDValue:      usd.String(),
 		Confirmations: 0,
 		MaxFinality:   0,
+		BatchID:       batchID,
+		ClientID:      req.ClientID,
 	}
 
-	// Eval inline rules
-	final := decision.Allow
-	var evv []events.Evidence
-	for _, rl := range s.rules {
-		if hit, dec, ev := rl.EvalInline(te); hit {
-			final = decision.Max(final, dec)
-			if ev.RuleID != "" {
-				evv = append(evv, ev)
-			}
-		}
+	// Eval inline rules against one snapshot within the latency budget; rules
+	// cut off by the budget contribute their fallback decision
+	evalCtx, cancel := s.withBudget(ctx)
+	out := snap.EvalInline(evalCtx, te, s.state)
+	cancel()
+	final, evv := out.Decision, out.Evidence
+	if out.Degraded {
+		s.log.Warn("degraded decision", "event", eventID, "decision", final)
 	}
 
-	// publish provisional decision + synthetic tx event onto NATS for streamer
-	if b, err := te.Marshal(); err == nil {
-		_ = s.nc.Publish(natsjs.SubjTxEvent+".INLINE", b)
+	if err := ctx.Err(); err != nil {
+		return DecisionResp{}, err
 	}
+
 	prov := events.DecisionEvent{
 		SchemaVersion: events.SchemaVersion,
-		DecisionID:    randID(),
+		DecisionID:    decisionID,
 		EventID:       te.EventID,
+		UserID:        te.Subject.UserID,
 		IssuedAt:      time.Now(),
 		Stage:         "provisional",
 		Decision:      final,
 		DecisionCode:  pickCode(final, evv),
-		PolicyVersion: s.policyVersion,
+		PolicyVersion: snap.Version,
 		Evidence:      evv,
+		BatchID:       batchID,
+		ClientID:      req.ClientID,
 	}
 
-	if b, err := prov.Marshal(); err == nil {
-		_ = s.nc.Publish(natsjs.SubjDecisionProv, b)
+	// publish synthetic tx event + provisional decision onto NATS for streamer,
+	// in that order; message IDs let JetStream drop re-publishes of the same event
+	degraded := out.Degraded
+	if err := s.record(ctx, te, &prov); err != nil {
+		reason := notRecordedReason(err)
+		if s.cfg.Outbox.FullPolicy != "degrade" {
+			s.metrics.notRecorded.Add(1)
+			s.log.Error("decision not recorded, refusing", "event", eventID, "err", err)
+			return DecisionResp{}, fmt.Errorf("%w: %v", errNotRecorded, err)
+		}
+		// answer, but conservatively: nothing downstream will see this decision
+		fd := s.cfg.Outbox.FullDecision
+		if fd == "" {
+			fd = decision.HoldAuto
+		}
+		final = decision.Max(final, fd)
+		evv = append(evv, events.Evidence{RuleID: "OUTBOX", Key: "degraded", Value: rules.DegradedEvidence{Class: "outbox", Reason: reason, Detail: err.Error(), Fallback: fd}, Degraded: true})
+		prov.Decision, prov.DecisionCode, prov.Evidence = final, pickCode(final, evv), evv
+		degraded = true
+		s.metrics.notRecordedDegraded.Add(1)
+		s.log.Warn("decision not recorded, degrading", "event", eventID, "decision", final, "err", err)
+	}
+	s.decisions.add(prov)
+	if te.Counterparty != "" {
+		s.state.AddFlow(te.Subject.UserID, state.Flow{EventID: te.EventID, At: te.OccurredAt, Direction: te.Direction, USD: usd, Chain: te.Chain, Counterparty: te.Counterparty})
 	}
 
-	resp := DecisionResp{Decision: final, DecisionCode: prov.DecisionCode, PolicyVersion: s.policyVersion, Evidence: evv}
-	_ = json.NewEncoder(w).Encode(resp)
-	dur := time.Since(start)
-	if dur > time.Duration(s.cfg.LatencyBudgetMS)*time.Millisecond {
-		s.log.Warn("decision latency over budget", "ms", dur.Milliseconds())
+	resp := DecisionResp{DecisionID: prov.DecisionID, EventID: te.EventID, Decision: final, DecisionCode: prov.DecisionCode, PolicyVersion: snap.Version, Evidence: evv, Degraded: degraded}
+	if ttl := snap.TTL(final, evv); ttl > 0 {
+		exp := prov.IssuedAt.Add(ttl)
+		resp.ExpiresAt = &exp
+	}
+	return resp, nil
+}
+
+// withBudget bounds rule evaluation by the configured latency budget.
+func (s *Server) withBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.cfg.LatencyBudgetMS <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(s.cfg.LatencyBudgetMS)*time.Millisecond)
+}
+
+// Degradation reasons when a decision's events could not be recorded: the
+// outbox had no room, or publishing and buffering failed otherwise (disk,
+// encoding).
+const (
+	degradedOutboxFull  = "outbox_full"
+	degradedNotRecorded = "not_recorded"
+)
+
+func notRecordedReason(err error) string {
+	if errors.Is(err, outbox.ErrFull) {
+		return degradedOutboxFull
 	}
+	return degradedNotRecorded
+}
+
+const defaultPublishTimeout = 250 * time.Millisecond
+
+// record publishes the tx event and its provisional decision, through the
+// outbox when enabled. The publish is bounded by the request and the outbox
+// publish timeout; what is not acked by then is buffered. Without an outbox,
+// publish errors are only logged.
+func (s *Server) record(ctx context.Context, te *events.TxEvent, prov *events.DecisionEvent) error {
+	tb, err := te.Marshal()
+	if err != nil {
+		return err
+	}
+	db, err := prov.Marshal()
+	if err != nil {
+		return err
+	}
+	recs := []outbox.Record{
+		{Subject: natsjs.SubjTxEvent, MsgID: te.EventID, Data: tb},
+		{Subject: natsjs.DecisionSubject(natsjs.SubjDecisionProv, prov.UserID), MsgID: prov.DecisionID, Data: db},
+	}
+	timeout := defaultPublishTimeout
+	if s.cfg.Outbox.PublishTimeoutMS > 0 {
+		timeout = time.Duration(s.cfg.Outbox.PublishTimeoutMS) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if s.out != nil {
+		return s.out.Publish(ctx, recs...)
+	}
+	if n, err := s.pub(ctx, recs); err != nil {
+		for _, r := range recs[n:] {
+			s.log.Error("publish lost", "subject", r.Subject, "msg_id", r.MsgID, "err", err)
+		}
+	}
+	return nil
+}
+
+// publishRecords publishes recs through JetStream and waits for the stream
+// acks; a Nats-Msg-Id header on each lets JetStream drop retried duplicates.
+func (s *Server) publishRecords(ctx context.Context, recs []outbox.Record) (int, error) {
+	msgs := make([]*nats.Msg, len(recs))
+	for i, r := range recs {
+		msgs[i] = &nats.Msg{Subject: r.Subject, Data: r.Data, Header: nats.Header{nats.MsgIdHdr: []string{r.MsgID}}}
+	}
+	return natsjs.Publish(ctx, s.js, msgs...)
+}
+
+// StatusResp reports the active policy and screening list versions.
+type StatusResp struct {
+	PolicyVersion string        `json:"policy_version"`
+	Lists         []ListStatus  `json:"lists"`
+	NATS          string        `json:"nats"`
+	Outbox        *outbox.Stats `json:"outbox,omitempty"`
+}
+
+type ListStatus struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Source  string `json:"source"`
+	Entries int    `json:"entries"`
+	Invalid int    `json:"invalid,omitempty"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	snap := s.active.Load()
+	resp := StatusResp{PolicyVersion: snap.Version, Lists: []ListStatus{}, NATS: s.nc.Status().String()}
+	if s.out != nil {
+		st := s.out.Stats()
+		resp.Outbox = &st
+	}
+	for _, l := range snap.Lists.Lists() {
+		resp.Lists = append(resp.Lists, ListStatus{Name: l.Name, Version: l.Version, Source: l.Source, Entries: l.Len(), Invalid: l.Invalid})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
 }
 
 func pickCode(dec string, ev []events.Evidence) string {
-	if len(ev) == 0 {
+	if len(ev) == 0 || dec == decision.Allow {
 		return "OK"
 	}
 	// pick highest severity first element
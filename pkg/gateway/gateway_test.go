@@ -0,0 +1,184 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/christophercampbell/riskr/pkg/config"
+	"github.com/christophercampbell/riskr/pkg/decision"
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/outbox"
+	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+	"github.com/christophercampbell/riskr/pkg/state"
+)
+
+const sanctioned = "0x000000000000000000000000000000000000dEaD"
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+func newTestServer() *Server {
+	s := &Server{cfg: &config.Config{}, log: nopLogger{}, idem: newIdemCache(0, nil, nopLogger{}), cache: newDecisionCache(), decisions: newDecisionView(), auth: &authenticator{}, state: state.NewMem()}
+	s.pub = func(_ context.Context, recs []outbox.Record) (int, error) { return len(recs), nil }
+	return s
+}
+
+func writeList(t *testing.T, dir, version string) string {
+	t.Helper()
+	path := filepath.Join(dir, version+".txt")
+	if err := os.WriteFile(path, []byte("# version: "+version+"\n"+sanctioned+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestEvaluateConcurrentReload evaluates while policies are reloaded and list
+// versions swapped; run with -race. Each decision must report the policy and
+// list versions of the snapshot it was evaluated against.
+func TestEvaluateConcurrentReload(t *testing.T) {
+	dir := t.TempDir()
+	versions := []string{"a", "b", "c"}
+	lists := make([]*sanctions.List, len(versions))
+	for i, v := range versions {
+		l, err := sanctions.LoadFile("L", writeList(t, dir, v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lists[i] = l
+	}
+	p := &policy.Policy{
+		Version: "p0",
+		Rules:   []policy.RuleDef{{ID: "R1_OFAC_ADDR", Type: "ofac_addr", Action: "REJECT_FATAL"}},
+		Lists:   []policy.ListDef{{Name: "L", Source: filepath.Join(dir, "a.txt")}},
+	}
+
+	reg := sanctions.NewRegistry(func(s string) string { return s }, nil)
+	if _, err := reg.Apply(p.Lists); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer()
+	if err := s.active.Follow(p, reg, func(err error) { t.Error(err) }); err != nil {
+		t.Fatal(err)
+	}
+
+	const rounds = 200
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := range rounds {
+			if _, err := reg.Swap(lists[i%len(lists)]); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := range rounds {
+			np := *p
+			np.Version = fmt.Sprintf("p%d", i)
+			if err := s.active.Apply(&np, reg); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rounds {
+				snap := s.active.Load()
+				want := snap.ListVersions()
+				req := &DecisionReq{Subject: sanctionedSubject(fmt.Sprintf("u%d-%d", w, i))}
+				resp, err := s.evaluate(context.Background(), snap, req, "")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if resp.PolicyVersion != snap.Version {
+					t.Errorf("policy version %s, snapshot %s", resp.PolicyVersion, snap.Version)
+				}
+				if len(resp.Evidence) != 1 || !strings.Contains(want, "L@"+resp.Evidence[0].ListVersion+",") {
+					t.Errorf("evidence %+v does not match snapshot lists %s", resp.Evidence, want)
+				}
+				if snap.ListVersions() != want {
+					t.Errorf("snapshot lists changed during evaluation: %s -> %s", want, snap.ListVersions())
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func sanctionedSubject(userID string) events.Subject {
+	return events.Subject{UserID: userID, Addresses: []string{sanctioned}}
+}
+
+func TestDecisionReadsScopedToClient(t *testing.T) {
+	s := newTestServer()
+	s.decisions.add(events.DecisionEvent{DecisionID: "d1", EventID: "e1", UserID: "u1", ClientID: "a"})
+	for _, tc := range []struct {
+		client string
+		want   int
+	}{{"a", http.StatusOK}, {"b", http.StatusNotFound}, {"", http.StatusOK}} {
+		r := httptest.NewRequest("GET", "/v1/decisions/d1", nil)
+		r.SetPathValue("id", "d1")
+		r = r.WithContext(withClientID(r.Context(), tc.client))
+		w := httptest.NewRecorder()
+		s.handleGetDecision(w, r)
+		if w.Code != tc.want {
+			t.Errorf("client %q: GET decision = %d, want %d", tc.client, w.Code, tc.want)
+		}
+		r = httptest.NewRequest("GET", "/v1/events/e1/decisions", nil)
+		r.SetPathValue("event_id", "e1")
+		r = r.WithContext(withClientID(r.Context(), tc.client))
+		w = httptest.NewRecorder()
+		s.handleEventDecisions(w, r)
+		if w.Code != tc.want {
+			t.Errorf("client %q: GET event decisions = %d, want %d", tc.client, w.Code, tc.want)
+		}
+	}
+}
+
+// A check to a destination the user never sent to is held in the sync answer;
+// once recorded, the destination is known to the next check.
+func TestCheckNewDestination(t *testing.T) {
+	dir := t.TempDir()
+	p := &policy.Policy{
+		Version: "p0",
+		Rules:   []policy.RuleDef{{ID: "R9_NEW_DEST", Type: "new_destination_address", Action: decision.HoldAuto}},
+		Lists:   []policy.ListDef{{Name: "L", Source: writeList(t, dir, "a")}},
+		Params:  map[string]any{"new_address_first_use_max_usd": 1000},
+	}
+	reg := sanctions.NewRegistry(func(s string) string { return s }, nil)
+	if _, err := reg.Apply(p.Lists); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer()
+	if err := s.active.Follow(p, reg, func(err error) { t.Error(err) }); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{decision.HoldAuto, decision.Allow} {
+		req := &DecisionReq{Subject: events.Subject{UserID: "u1"}}
+		req.Tx.Type, req.Tx.USDValue, req.Tx.DestAddress = "withdraw", 5000, "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"
+		resp, err := s.evaluate(context.Background(), s.active.Load(), req, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Decision != want {
+			t.Fatalf("check %d: decision = %s, want %s (%+v)", i, resp.Decision, want, resp.Evidence)
+		}
+	}
+}
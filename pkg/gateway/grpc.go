@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/christophercampbell/riskr/pkg/api/riskrv1"
+	"github.com/christophercampbell/riskr/pkg/events"
+)
+
+// grpcService implements riskrv1.DecisionServiceServer on top of the same
+// evaluation code as the HTTP and NATS APIs. A client deadline bounds the
+// whole call; rule evaluation is further bounded by the latency budget.
+type grpcService struct {
+	riskrv1.UnimplementedDecisionServiceServer
+	s *Server
+}
+
+func newGRPCServer(s *Server) *grpc.Server {
+	gs := grpc.NewServer()
+	riskrv1.RegisterDecisionServiceServer(gs, &grpcService{s: s})
+	return gs
+}
+
+func (g *grpcService) Check(ctx context.Context, in *riskrv1.CheckRequest) (*riskrv1.CheckResponse, error) {
+	resp, err := g.s.evaluate(ctx, g.s.active.Load(), fromPBRequest(in), "")
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return toPBResponse(&resp), nil
+}
+
+func (g *grpcService) CheckBatch(ctx context.Context, in *riskrv1.CheckBatchRequest) (*riskrv1.CheckBatchResponse, error) {
+	if len(in.GetItems()) == 0 || len(in.GetItems()) > maxBatchItems {
+		return nil, status.Errorf(codes.InvalidArgument, "batch must have 1..%d items", maxBatchItems)
+	}
+	items := make([]batchInput, len(in.GetItems()))
+	for i, it := range in.GetItems() {
+		items[i].req = fromPBRequest(it)
+	}
+	br := g.s.checkBatch(ctx, items)
+	out := &riskrv1.CheckBatchResponse{BatchId: br.BatchID, PolicyVersion: br.PolicyVersion}
+	for _, r := range br.Results {
+		item := &riskrv1.BatchItem{Index: int32(r.Index)}
+		if r.Result != nil {
+			item.Outcome = &riskrv1.BatchItem_Result{Result: toPBResponse(r.Result)}
+		} else {
+			item.Outcome = &riskrv1.BatchItem_Error{Error: r.Error}
+		}
+		out.Results = append(out.Results, item)
+	}
+	return out, nil
+}
+
+func (g *grpcService) GetDecision(ctx context.Context, in *riskrv1.GetDecisionRequest) (*riskrv1.GetDecisionResponse, error) {
+	res, ok := g.s.decisions.lookup(in.GetDecisionId(), clientID(ctx))
+	if !ok {
+		return nil, status.Error(codes.NotFound, "decision not found")
+	}
+	h := &riskrv1.DecisionHistory{EventId: res.History.EventID, Current: toPBEvent(&res.History.Current)}
+	for i := range res.History.Decisions {
+		h.Decisions = append(h.Decisions, toPBEvent(&res.History.Decisions[i]))
+	}
+	return &riskrv1.GetDecisionResponse{Decision: toPBEvent(&res.Decision), History: h}, nil
+}
+
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, errIdempotencyMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, errInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, errNotRecorded):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// ------------------------ conversions ------------------------
+
+func fromPBRequest(in *riskrv1.CheckRequest) *DecisionReq {
+	req := &DecisionReq{RequestID: in.GetRequestId(), Context: in.GetContext().AsMap()}
+	if sj := in.GetSubject(); sj != nil {
+		req.Subject = events.Subject{UserID: sj.UserId, AccountID: sj.AccountId, Addresses: sj.Addresses, GeoISO: sj.GeoIso, KYCTier: sj.KycLevel, Name: sj.Name, DOB: sj.Dob}
+	}
+	if tx := in.GetTx(); tx != nil {
+		req.Tx.Type, req.Tx.Asset, req.Tx.Amount, req.Tx.USDValue, req.Tx.DestAddress = tx.Type, tx.Asset, tx.Amount, tx.UsdValue, tx.DestAddress
+	}
+	return req
+}
+
+func toPBResponse(r *DecisionResp) *riskrv1.CheckResponse {
+	out := &riskrv1.CheckResponse{
+		DecisionId:    r.DecisionID,
+		EventId:       r.EventID,
+		Decision:      r.Decision,
+		DecisionCode:  r.DecisionCode,
+		PolicyVersion: r.PolicyVersion,
+		Evidence:      toPBEvidence(r.Evidence),
+		Replayed:      r.Replayed,
+		Cached:        r.Cached,
+		Degraded:      r.Degraded,
+	}
+	if r.ExpiresAt != nil {
+		out.ExpiresAt = timestamppb.New(*r.ExpiresAt)
+	}
+	return out
+}
+
+func toPBEvent(de *events.DecisionEvent) *riskrv1.DecisionEvent {
+	return &riskrv1.DecisionEvent{
+		SchemaVersion: de.SchemaVersion,
+		DecisionId:    de.DecisionID,
+		EventId:       de.EventID,
+		UserId:        de.UserID,
+		IssuedAt:      timestamppb.New(de.IssuedAt),
+		Stage:         de.Stage,
+		Decision:      de.Decision,
+		DecisionCode:  de.DecisionCode,
+		PolicyVersion: de.PolicyVersion,
+		Evidence:      toPBEvidence(de.Evidence),
+		BatchId:       de.BatchID,
+		ClientId:      de.ClientID,
+	}
+}
+
+func toPBEvidence(evv []events.Evidence) []*riskrv1.Evidence {
+	out := make([]*riskrv1.Evidence, 0, len(evv))
+	for _, ev := range evv {
+		out = append(out, &riskrv1.Evidence{RuleId: ev.RuleID, Key: ev.Key, Value: toPBValue(ev.Value), Limit: toPBValue(ev.Limit), List: ev.List, ListVersion: ev.ListVersion, Degraded: ev.Degraded})
+	}
+	return out
+}
+
+// toPBValue converts an evidence value (decimals, structs, ...) through its
+// JSON form, so gRPC clients see the same shape as HTTP clients.
+func toPBValue(v any) *structpb.Value {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return structpb.NewStringValue(fmt.Sprint(v))
+	}
+	pv := &structpb.Value{}
+	if err := protojson.Unmarshal(b, pv); err != nil {
+		return structpb.NewStringValue(string(b))
+	}
+	return pv
+}
@@ -2,27 +2,66 @@ package gateway
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/christophercampbell/riskr/pkg/config"
 	"github.com/christophercampbell/riskr/pkg/log"
 )
 
+// serveHTTP serves the HTTP API and, on the same listener, the gRPC
+// DecisionService (HTTP/2 with content-type application/grpc, cleartext h2c
+// or TLS). Both go through the auth middleware.
 func serveHTTP(ctx context.Context, cfg *config.Config, logger log.Logger, srv *Server) error {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/v1/decision/check", srv.handleDecision)
+	mux.HandleFunc("/v1/decision/check:batch", srv.handleBatch)
+	mux.HandleFunc("GET /v1/decisions/{id}", srv.handleGetDecision)
+	mux.HandleFunc("GET /v1/events/{event_id}/decisions", srv.handleEventDecisions)
+	mux.HandleFunc("GET /v1/subjects/{user_id}/decisions/stream", srv.handleDecisionStream)
+	mux.HandleFunc("/status", srv.handleStatus)
+	mux.HandleFunc("GET /metrics", srv.handleMetrics)
+
+	grpcSrv := newGRPCServer(srv)
+	handler := srv.auth.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcSrv.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	tlsCfg, err := serverTLS(cfg)
+	if err != nil {
+		return err
+	}
 
 	httpSrv := &http.Server{
 		Addr:         cfg.HTTP.ListenAddr,
-		Handler:      mux,
+		Handler:      handler,
+		Protocols:    protocols,
+		TLSConfig:    tlsCfg,
 		ReadTimeout:  time.Duration(cfg.HTTP.ReadTimeoutMS) * time.Millisecond,
 		WriteTimeout: time.Duration(cfg.HTTP.WriteTimeoutMS) * time.Millisecond,
 	}
 
 	errCh := make(chan error, 1)
 	go func() {
-		logger.Info("gateway starting", "http", cfg.HTTP.ListenAddr)
+		logger.Info("gateway starting", "http", cfg.HTTP.ListenAddr, "grpc", true, "tls", tlsCfg != nil)
+		if tlsCfg != nil {
+			errCh <- httpSrv.ListenAndServeTLS(cfg.ResolvePath(cfg.HTTP.TLSCertFile), cfg.ResolvePath(cfg.HTTP.TLSKeyFile))
+			return
+		}
 		errCh <- httpSrv.ListenAndServe()
 	}()
 
@@ -36,3 +75,29 @@ func serveHTTP(ctx context.Context, cfg *config.Config, logger log.Logger, srv *
 		return err
 	}
 }
+
+// serverTLS returns the TLS config when a certificate is configured. With a
+// client CA, presented client certificates are verified (mTLS); they are not
+// required, so clients may still use the other auth methods.
+func serverTLS(cfg *config.Config) (*tls.Config, error) {
+	if cfg.HTTP.TLSCertFile == "" {
+		if cfg.HTTP.ClientCAFile != "" {
+			return nil, errors.New("http.client_ca_file needs http.tls_cert_file")
+		}
+		return nil, nil
+	}
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.HTTP.ClientCAFile != "" {
+		b, err := os.ReadFile(cfg.ResolvePath(cfg.HTTP.ClientCAFile))
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.New("http.client_ca_file: no certificates")
+		}
+		tc.ClientCAs = pool
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tc, nil
+}
@@ -0,0 +1,265 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/christophercampbell/riskr/pkg/config"
+	"github.com/christophercampbell/riskr/pkg/log"
+)
+
+// IdempotencyHeader lets clients retry a decision check safely: a retry with
+// the same key (and body) within the TTL gets the original decision back and
+// publishes nothing new.
+const IdempotencyHeader = "Idempotency-Key"
+
+const defaultIdempotencyTTL = config.DefaultIdempotencyTTL
+
+var (
+	errIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+	errIdemNotFound        = errors.New("idempotency key not found")
+	errIdemConflict        = errors.New("idempotency key changed concurrently")
+)
+
+const (
+	// idemClaimTTL is how long an instance may take to decide a claimed key
+	// before another instance takes it over.
+	idemClaimTTL = 5 * time.Second
+	idemPoll     = 20 * time.Millisecond
+)
+
+// idemCache remembers decisions by idempotency key: locally, so concurrent
+// requests with the same key wait for the first one instead of evaluating
+// twice, and in a store shared by the gateway instances, so a retry routed to
+// another instance gets the original decision back too.
+type idemCache struct {
+	ttl   time.Duration
+	store idemStore // nil: this instance only
+	log   log.Logger
+
+	mu      sync.Mutex
+	entries map[string]*idemEntry
+	sweepAt time.Time
+}
+
+type idemEntry struct {
+	fingerprint string
+	expires     time.Time
+	done        chan struct{} // closed once resp/err are set
+	resp        DecisionResp
+	err         error
+}
+
+// idemStore holds idemRecords shared across instances. Writes are
+// conditional on the revision last read, as in a JetStream KV bucket.
+type idemStore interface {
+	get(key string) (idemRecord, uint64, error) // errIdemNotFound if absent
+	create(key string, rec idemRecord) (uint64, error)
+	update(key string, rec idemRecord, rev uint64) (uint64, error)
+	delete(key string, rev uint64) error
+}
+
+// idemRecord is a key claimed by an instance deciding it, or its decision.
+type idemRecord struct {
+	Fingerprint  string        `json:"fingerprint"`
+	ClaimedUntil time.Time     `json:"claimed_until,omitzero"`
+	Resp         *DecisionResp `json:"resp,omitempty"`
+}
+
+func newIdemCache(ttl time.Duration, store idemStore, logger log.Logger) *idemCache {
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	return &idemCache{ttl: ttl, store: store, log: logger, entries: make(map[string]*idemEntry)}
+}
+
+// do returns the decision recorded for key, or runs eval and records its
+// result. replayed reports whether the result was recorded before. Failed
+// evaluations are not recorded, so a corrected retry is evaluated afresh.
+func (c *idemCache) do(ctx context.Context, key, fingerprint string, eval func() (DecisionResp, error)) (resp DecisionResp, replayed bool, err error) {
+	now := time.Now()
+	c.mu.Lock()
+	c.sweepLocked(now)
+	if e, ok := c.entries[key]; ok && now.Before(e.expires) {
+		c.mu.Unlock()
+		if e.fingerprint != fingerprint {
+			return DecisionResp{}, false, errIdempotencyMismatch
+		}
+		<-e.done
+		if e.err != nil {
+			return c.do(ctx, key, fingerprint, eval) // first attempt failed and was dropped; evaluate again
+		}
+		return e.resp, true, nil
+	}
+	e := &idemEntry{fingerprint: fingerprint, expires: now.Add(c.ttl), done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	e.resp, replayed, e.err = c.shared(ctx, key, fingerprint, eval)
+	if e.err != nil {
+		c.mu.Lock()
+		if c.entries[key] == e {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+	}
+	close(e.done)
+	return e.resp, replayed, e.err
+}
+
+// shared looks key up in the store: a decision there is returned, a live
+// claim by another instance is waited for, and otherwise this instance claims
+// key and evaluates. Without a usable store it just evaluates.
+func (c *idemCache) shared(ctx context.Context, key, fingerprint string, eval func() (DecisionResp, error)) (DecisionResp, bool, error) {
+	if c.store == nil {
+		resp, err := eval()
+		return resp, false, err
+	}
+	sk := storeKey(key)
+	claim := func() idemRecord {
+		return idemRecord{Fingerprint: fingerprint, ClaimedUntil: time.Now().Add(idemClaimTTL)}
+	}
+	for {
+		rec, rev, err := c.store.get(sk)
+		switch {
+		case errors.Is(err, errIdemNotFound):
+			if rev, err = c.store.create(sk, claim()); err == nil {
+				return c.settle(sk, fingerprint, rev, eval)
+			}
+		case err != nil:
+		case rec.Fingerprint != fingerprint:
+			return DecisionResp{}, false, errIdempotencyMismatch
+		case rec.Resp != nil:
+			return *rec.Resp, true, nil
+		case time.Now().After(rec.ClaimedUntil):
+			// the claiming instance failed or went away; take the key over
+			if rev, err = c.store.update(sk, claim(), rev); err == nil {
+				return c.settle(sk, fingerprint, rev, eval)
+			}
+		default:
+			err = errIdemConflict // claimed by another instance: wait for its decision
+			select {
+			case <-ctx.Done():
+				return DecisionResp{}, false, ctx.Err()
+			case <-time.After(idemPoll):
+			}
+		}
+		if errors.Is(err, errIdemConflict) {
+			continue
+		}
+		c.log.Warn("idempotency store unavailable, deciding locally", "err", err)
+		resp, err := eval()
+		return resp, false, err
+	}
+}
+
+// settle evaluates a key claimed at rev and records the decision, or drops
+// the claim if evaluation failed.
+func (c *idemCache) settle(sk, fingerprint string, rev uint64, eval func() (DecisionResp, error)) (DecisionResp, bool, error) {
+	resp, err := eval()
+	if err != nil {
+		if derr := c.store.delete(sk, rev); derr != nil {
+			c.log.Warn("idempotency claim release", "err", derr)
+		}
+		return resp, false, err
+	}
+	if _, err := c.store.update(sk, idemRecord{Fingerprint: fingerprint, Resp: &resp}, rev); err != nil {
+		c.log.Warn("idempotency store update", "err", err)
+	}
+	return resp, false, nil
+}
+
+// sweepLocked drops expired entries at most once per TTL.
+func (c *idemCache) sweepLocked(now time.Time) {
+	if now.Before(c.sweepAt) {
+		return
+	}
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.sweepAt = now.Add(c.ttl)
+}
+
+// idemKey scopes a client key to the subject, and to the API client when
+// authenticated, so keys from different users or clients cannot collide.
+func idemKey(req *DecisionReq) string {
+	if req.ClientID != "" {
+		return req.ClientID + "/" + req.Subject.UserID + "/" + req.RequestID
+	}
+	return req.Subject.UserID + "/" + req.RequestID
+}
+
+// fingerprint identifies the request body a key was first used with.
+func fingerprint(req *DecisionReq) string {
+	cp := *req
+	cp.RequestID = ""
+	b, _ := json.Marshal(cp)
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// storeKey maps an idempotency key to a valid KV key.
+func storeKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// kvIdemStore is an idemStore in a JetStream KV bucket; the bucket TTL
+// expires records.
+type kvIdemStore struct {
+	kv nats.KeyValue
+}
+
+func (s kvIdemStore) get(key string) (idemRecord, uint64, error) {
+	e, err := s.kv.Get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return idemRecord{}, 0, errIdemNotFound
+	}
+	if err != nil {
+		return idemRecord{}, 0, err
+	}
+	var rec idemRecord
+	if err := json.Unmarshal(e.Value(), &rec); err != nil {
+		return idemRecord{}, 0, err
+	}
+	return rec, e.Revision(), nil
+}
+
+func (s kvIdemStore) create(key string, rec idemRecord) (uint64, error) {
+	b, _ := json.Marshal(rec)
+	return kvConflict(s.kv.Create(key, b))
+}
+
+func (s kvIdemStore) update(key string, rec idemRecord, rev uint64) (uint64, error) {
+	b, _ := json.Marshal(rec)
+	return kvConflict(s.kv.Update(key, b, rev))
+}
+
+func (s kvIdemStore) delete(key string, rev uint64) error {
+	_, err := kvConflict(0, s.kv.Delete(key, nats.LastRevision(rev)))
+	return err
+}
+
+// kvConflict maps a failed conditional KV write to errIdemConflict.
+func kvConflict(rev uint64, err error) (uint64, error) {
+	if errors.Is(err, nats.ErrKeyExists) {
+		return 0, errIdemConflict
+	}
+	return rev, err
+}
+
+// stableID derives an ID from the idempotency key so events re-published after
+// a gateway restart carry the same Nats-Msg-Id and JetStream drops them.
+func stableID(key, kind string) string {
+	h := sha256.Sum256([]byte(key + "/" + kind))
+	return hex.EncodeToString(h[:8])
+}
@@ -0,0 +1,172 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memIdemStore is an idemStore in memory whose records expire ttl after
+// their last write, like the KV bucket.
+type memIdemStore struct {
+	ttl time.Duration
+
+	mu   sync.Mutex
+	rev  uint64
+	recs map[string]memIdemRecord
+}
+
+type memIdemRecord struct {
+	rec     idemRecord
+	rev     uint64
+	expires time.Time
+}
+
+func newMemIdemStore(ttl time.Duration) *memIdemStore {
+	return &memIdemStore{ttl: ttl, recs: make(map[string]memIdemRecord)}
+}
+
+func (s *memIdemStore) getLocked(key string) (memIdemRecord, bool) {
+	r, ok := s.recs[key]
+	if ok && time.Now().After(r.expires) {
+		delete(s.recs, key)
+		return memIdemRecord{}, false
+	}
+	return r, ok
+}
+
+func (s *memIdemStore) get(key string) (idemRecord, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.getLocked(key)
+	if !ok {
+		return idemRecord{}, 0, errIdemNotFound
+	}
+	return r.rec, r.rev, nil
+}
+
+func (s *memIdemStore) create(key string, rec idemRecord) (uint64, error) {
+	return s.update(key, rec, 0)
+}
+
+func (s *memIdemStore) update(key string, rec idemRecord, rev uint64) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, _ := s.getLocked(key); r.rev != rev {
+		return 0, errIdemConflict
+	}
+	s.rev++
+	s.recs[key] = memIdemRecord{rec: rec, rev: s.rev, expires: time.Now().Add(s.ttl)}
+	return s.rev, nil
+}
+
+func (s *memIdemStore) delete(key string, rev uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, _ := s.getLocked(key); r.rev != rev {
+		return errIdemConflict
+	}
+	delete(s.recs, key)
+	return nil
+}
+
+// decider returns an eval func answering dec and counting its calls.
+func decider(dec string, calls *int) func() (DecisionResp, error) {
+	return func() (DecisionResp, error) {
+		*calls++
+		return DecisionResp{DecisionID: "d1", Decision: dec}, nil
+	}
+}
+
+// Two instances sharing the store: a retry on the second gets the first's
+// decision, even when its own evaluation would differ.
+func TestIdempotencyReplayAcrossInstances(t *testing.T) {
+	store := newMemIdemStore(time.Minute)
+	a, b := newIdemCache(time.Minute, store, nopLogger{}), newIdemCache(time.Minute, store, nopLogger{})
+	ctx := context.Background()
+	var callsA, callsB int
+
+	first, replayed, err := a.do(ctx, "k", "fp", decider("HOLD_AUTO", &callsA))
+	if err != nil || replayed {
+		t.Fatalf("first: replayed=%v err=%v", replayed, err)
+	}
+	retry, replayed, err := b.do(ctx, "k", "fp", decider("ALLOW", &callsB))
+	if err != nil || !replayed || retry.Decision != first.Decision || callsB != 0 {
+		t.Fatalf("retry = %s replayed=%v err=%v evals=%d, want %s replayed", retry.Decision, replayed, err, callsB, first.Decision)
+	}
+	if _, _, err := b.do(ctx, "k", "other", decider("ALLOW", &callsB)); !errors.Is(err, errIdempotencyMismatch) {
+		t.Fatalf("different request: err = %v, want mismatch", err)
+	}
+	if _, _, err := a.do(ctx, "k", "other", decider("ALLOW", &callsA)); !errors.Is(err, errIdempotencyMismatch) {
+		t.Fatalf("different request on first instance: err = %v, want mismatch", err)
+	}
+}
+
+func TestIdempotencyExpires(t *testing.T) {
+	const ttl = 30 * time.Millisecond
+	store := newMemIdemStore(ttl)
+	a, b := newIdemCache(ttl, store, nopLogger{}), newIdemCache(ttl, store, nopLogger{})
+	ctx := context.Background()
+	var calls int
+	if _, _, err := a.do(ctx, "k", "fp", decider("HOLD_AUTO", &calls)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * ttl)
+	for _, c := range []*idemCache{a, b} {
+		if _, replayed, err := c.do(ctx, "k", "other", decider("ALLOW", &calls)); err != nil || replayed {
+			t.Fatalf("after ttl: replayed=%v err=%v, want a fresh evaluation", replayed, err)
+		}
+		time.Sleep(2 * ttl)
+	}
+	if calls != 3 {
+		t.Fatalf("evaluations = %d, want 3", calls)
+	}
+}
+
+// A retry arriving while another instance decides waits for that decision;
+// a claim left by a failed instance is taken over once it lapses.
+func TestIdempotencyClaims(t *testing.T) {
+	store := newMemIdemStore(time.Minute)
+	a, b := newIdemCache(time.Minute, store, nopLogger{}), newIdemCache(time.Minute, store, nopLogger{})
+	ctx := context.Background()
+
+	inA, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		_, _, _ = a.do(ctx, "k", "fp", func() (DecisionResp, error) {
+			close(inA)
+			<-release
+			return DecisionResp{Decision: "HOLD_AUTO"}, nil
+		})
+	}()
+	<-inA
+	time.AfterFunc(50*time.Millisecond, func() { close(release) })
+	var calls int
+	resp, replayed, err := b.do(ctx, "k", "fp", decider("ALLOW", &calls))
+	if err != nil || !replayed || resp.Decision != "HOLD_AUTO" || calls != 0 {
+		t.Fatalf("while claimed: %s replayed=%v err=%v evals=%d", resp.Decision, replayed, err, calls)
+	}
+
+	if _, err := store.create(storeKey("stale"), idemRecord{Fingerprint: "fp", ClaimedUntil: time.Now().Add(-time.Second)}); err != nil {
+		t.Fatal(err)
+	}
+	if resp, replayed, err := b.do(ctx, "stale", "fp", decider("ALLOW", &calls)); err != nil || replayed || resp.Decision != "ALLOW" {
+		t.Fatalf("stale claim: %s replayed=%v err=%v", resp.Decision, replayed, err)
+	}
+}
+
+// A failed evaluation releases its claim so a corrected retry is evaluated.
+func TestIdempotencyFailureReleasesClaim(t *testing.T) {
+	store := newMemIdemStore(time.Minute)
+	a, b := newIdemCache(time.Minute, store, nopLogger{}), newIdemCache(time.Minute, store, nopLogger{})
+	ctx := context.Background()
+	fail := func() (DecisionResp, error) { return DecisionResp{}, errNotRecorded }
+	if _, _, err := a.do(ctx, "k", "fp", fail); !errors.Is(err, errNotRecorded) {
+		t.Fatalf("err = %v", err)
+	}
+	var calls int
+	if _, replayed, err := b.do(ctx, "k", "fp", decider("ALLOW", &calls)); err != nil || replayed || calls != 1 {
+		t.Fatalf("retry: replayed=%v err=%v evals=%d", replayed, err, calls)
+	}
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// metrics are the gateway counters exported on /metrics.
+type metrics struct {
+	notRecorded         atomic.Uint64 // checks refused because their events could not be recorded
+	notRecordedDegraded atomic.Uint64 // checks answered degraded for the same reason
+}
+
+// handleMetrics serves the outbox and recording metrics in the Prometheus
+// text exposition format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetric(w, "riskr_gateway_not_recorded_total", "counter", "Checks whose events could not be recorded, by full policy outcome.",
+		labeled{`outcome="refused"`, s.metrics.notRecorded.Load()},
+		labeled{`outcome="degraded"`, s.metrics.notRecordedDegraded.Load()})
+	if s.out == nil {
+		return
+	}
+	st := s.out.Stats()
+	var full, age float64
+	if st.Full {
+		full = 1
+	}
+	if st.OldestAt != nil {
+		age = time.Since(*st.OldestAt).Seconds()
+	}
+	writeMetric(w, "riskr_outbox_pending_records", "gauge", "Records waiting for replay.", labeled{"", st.Pending})
+	writeMetric(w, "riskr_outbox_pending_bytes", "gauge", "On-disk size of pending records.", labeled{"", st.Bytes})
+	writeMetric(w, "riskr_outbox_max_bytes", "gauge", "Outbox capacity; 0 is unbounded.", labeled{"", st.MaxBytes})
+	writeMetric(w, "riskr_outbox_full", "gauge", "1 if the last buffered publish was refused for lack of room.", labeled{"", full})
+	writeMetric(w, "riskr_outbox_oldest_age_seconds", "gauge", "Age of the oldest pending record.", labeled{"", age})
+	writeMetric(w, "riskr_outbox_enqueued_total", "counter", "Records buffered since start.", labeled{"", st.Enqueued})
+	writeMetric(w, "riskr_outbox_replayed_total", "counter", "Buffered records published since start.", labeled{"", st.Replayed})
+	writeMetric(w, "riskr_outbox_refused_total", "counter", "Records refused because the outbox was full.", labeled{"", st.Refused})
+}
+
+// labeled is one sample of a metric: its label set (without braces) and value.
+type labeled struct {
+	labels string
+	value  any
+}
+
+func writeMetric(w io.Writer, name, typ, help string, samples ...labeled) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for _, s := range samples {
+		if s.labels != "" {
+			fmt.Fprintf(w, "%s{%s} %v\n", name, s.labels, s.value)
+		} else {
+			fmt.Fprintf(w, "%s %v\n", name, s.value)
+		}
+	}
+}
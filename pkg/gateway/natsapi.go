@@ -0,0 +1,110 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/christophercampbell/riskr/pkg/natsjs"
+)
+
+// checkQueue is the queue group gateway instances share for request/reply checks.
+const checkQueue = "riskr-gateway"
+
+// checkWorkers is how many request/reply checks an instance evaluates at once.
+const checkWorkers = 64
+
+var errShuttingDown = errors.New("shutting down")
+
+// Error headers on request/reply responses, as used by NATS micro services.
+const (
+	hdrServiceError     = "Nats-Service-Error"
+	hdrServiceErrorCode = "Nats-Service-Error-Code"
+)
+
+// ErrorResp is the reply body when a request/reply check fails.
+type ErrorResp struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+// serveChecks subscribes to the request/reply check subject and serves its
+// messages on checkWorkers goroutines until ctx ends. NATS delivers a
+// subscription's messages one at a time, so the callback only hands them
+// off; while all workers are busy it blocks and messages queue in the
+// subscription.
+func (s *Server) serveChecks(ctx context.Context) error {
+	checks := make(chan *nats.Msg)
+	for range checkWorkers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case m := <-checks:
+					s.handleCheckMsg(ctx, m)
+				}
+			}
+		}()
+	}
+	_, err := natsjs.SubscribeQueue(ctx, s.nc, natsjs.SubjDecisionCheck, checkQueue, func(m *nats.Msg) {
+		select {
+		case checks <- m:
+		case <-ctx.Done():
+			s.replyError(m, http.StatusServiceUnavailable, errShuttingDown)
+		}
+	})
+	return err
+}
+
+// handleCheckMsg serves a DecisionReq on the request/reply subject with the
+// same semantics as POST /v1/decision/check, including the Idempotency-Key
+// header and authentication (see authenticateMsg).
+func (s *Server) handleCheckMsg(ctx context.Context, m *nats.Msg) {
+	if m.Reply == "" {
+		return // nowhere to send the decision
+	}
+	start := time.Now()
+	id, err := s.auth.authenticateMsg(m)
+	if err != nil {
+		s.log.Warn("unauthenticated request", "subject", m.Subject, "err", err)
+		s.replyError(m, http.StatusUnauthorized, errUnauthenticated)
+		return
+	}
+	var req DecisionReq
+	if err := json.Unmarshal(m.Data, &req); err != nil {
+		s.replyError(m, http.StatusBadRequest, err)
+		return
+	}
+	if k := m.Header.Get(IdempotencyHeader); k != "" {
+		req.RequestID = k
+	}
+	resp, err := s.evaluate(withClientID(ctx, id), s.active.Load(), &req, "")
+	if err != nil {
+		s.replyError(m, httpStatus(err), err)
+		return
+	}
+	b, _ := json.Marshal(resp)
+	if err := m.Respond(b); err != nil {
+		s.log.Error("check reply", "err", err)
+	}
+	dur := time.Since(start)
+	if dur > time.Duration(s.cfg.LatencyBudgetMS)*time.Millisecond {
+		s.log.Warn("decision latency over budget", "ms", dur.Milliseconds())
+	}
+}
+
+func (s *Server) replyError(m *nats.Msg, code int, err error) {
+	b, _ := json.Marshal(ErrorResp{Error: err.Error(), Code: code})
+	rm := &nats.Msg{Data: b, Header: nats.Header{}}
+	rm.Header.Set(hdrServiceError, err.Error())
+	rm.Header.Set(hdrServiceErrorCode, strconv.Itoa(code))
+	if rerr := m.RespondMsg(rm); rerr != nil {
+		s.log.Error("check reply", "err", rerr)
+	}
+}
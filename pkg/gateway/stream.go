@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/natsjs"
+)
+
+const sseHeartbeat = 15 * time.Second
+
+// handleDecisionStream streams a subject's decisions as server-sent events.
+// Each event's id is its DECISIONS stream sequence; a reconnecting client
+// sends it back as Last-Event-ID (or ?since=<seq>) and resumes right after it.
+// Without either, only new decisions are sent. Authenticated callers only see
+// decisions of their own checks.
+func (s *Server) handleDecisionStream(w http.ResponseWriter, r *http.Request) {
+	userID, client := r.PathValue("user_id"), clientID(r.Context())
+	since := r.Header.Get("Last-Event-ID")
+	if since == "" {
+		since = r.URL.Query().Get("since")
+	}
+	start := nats.DeliverNew()
+	if since != "" {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid last event id", http.StatusBadRequest)
+			return
+		}
+		start = nats.StartSequence(seq + 1)
+	}
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{}) // long-lived; the server write timeout does not apply
+
+	msgs := make(chan *nats.Msg, 256)
+	// the consumer is filtered to the user's subjects; the server skips
+	// everyone else's decisions
+	sub, err := s.js.ChanSubscribe(natsjs.UserDecisions(userID), msgs, nats.BindStream(natsjs.StreamDecisions), nats.OrderedConsumer(), start)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+	s.log.Info("decision stream opened", "user", userID, "since", since)
+
+	hb := time.NewTicker(sseHeartbeat)
+	defer hb.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			s.log.Info("decision stream closed", "user", userID)
+			return
+		case <-hb.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case m := <-msgs:
+			var de events.DecisionEvent
+			if err := de.Unmarshal(m.Data); err != nil || de.UserID != userID || !visible(de, client) {
+				continue
+			}
+			md, err := m.Metadata()
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", md.Sequence.Stream, de.Stage, m.Data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
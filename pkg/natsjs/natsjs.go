@@ -6,7 +6,10 @@ package natsjs
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -15,20 +18,47 @@ import (
 )
 
 const (
-	StreamEvents    = "EVENTS"
-	StreamDecisions = "DECISIONS"
-	StreamPolicy    = "POLICY"
+	StreamEvents     = "EVENTS"
+	StreamDecisions  = "DECISIONS"
+	StreamPolicy     = "POLICY"
+	StreamWebhookDLQ = "WEBHOOK_DLQ"
 
 	SubjTxEvent = "riskr.events.tx"
 
+	// decisions are published per user, see DecisionSubject
 	SubjDecisionProv     = "riskr.decisions.provisional"
 	SubjDecisionFinal    = "riskr.decisions.final"
 	SubjDecisionOverride = "riskr.decisions.override"
+	SubjDecisionsAll     = "riskr.decisions.>"
+	SubjDecisionCheck    = "riskr.decisions.check" // request/reply decision API (core NATS, not streamed)
 
 	SubjPolicyApply     = "riskr.policies.apply"   // CLI publishes new signed policy versions
 	SubjPolicyBroadcast = "riskr.policies.current" // streamer rebroadcasts active policy payload
+
+	SubjWebhookDLQ = "riskr.webhooks.dlq" // + ".<endpoint>"; undeliverable decisions
+
+	BucketSanctions   = "riskr-sanctions"   // object store holding published screening list versions
+	BucketIdempotency = "riskr-idempotency" // decisions by idempotency key, shared by gateway instances
 )
 
+// DecisionSubject is the subject a decision of stage subject base (e.g.
+// SubjDecisionProv) for userID is published on: base plus a token derived
+// from the user ID, which need not be a valid subject token and is kept out
+// of subjects.
+func DecisionSubject(base, userID string) string {
+	return base + "." + userToken(userID)
+}
+
+// UserDecisions is the filter subject matching every decision for userID.
+func UserDecisions(userID string) string {
+	return "riskr.decisions.*." + userToken(userID)
+}
+
+func userToken(userID string) string {
+	h := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(h[:12])
+}
+
 // Connect dials NATS and returns an *nats.Conn* bound to ctx lifetime.
 // The caller must not Close() the connection if ctx is still live unless shutting down.
 func Connect(ctx context.Context, urls []string, opts ...nats.Option) (*nats.Conn, error) {
@@ -58,28 +88,43 @@ func JetStream(nc *nats.Conn) (nats.JetStreamContext, error) {
 	return nc.JetStream(nats.PublishAsyncMaxPending(256))
 }
 
+// DefaultDuplicates is the JetStream duplicate window (its server default).
+const DefaultDuplicates = 2 * time.Minute
+
 // Bootstrap ensures the core streams required by riskr exist (events + policy).
 // Safe to call multiple times; streams are updated if they already exist.
-func Bootstrap(js nats.JetStreamContext) error {
+// Events and decisions are deduplicated by Nats-Msg-Id over at least dedupe
+// (the gateway idempotency TTL), so a retried check or a replayed outbox
+// record within it is stored once.
+func Bootstrap(js nats.JetStreamContext, dedupe time.Duration) error {
+	if dedupe < DefaultDuplicates {
+		dedupe = DefaultDuplicates
+	}
 	eventsCfg := &nats.StreamConfig{
-		Name:      StreamEvents,
-		Subjects:  []string{SubjTxEvent},
-		Retention: nats.LimitsPolicy,
-		Storage:   nats.FileStorage,
-		NoAck:     false,
-		Replicas:  1,
+		Name:       StreamEvents,
+		Subjects:   []string{SubjTxEvent},
+		Retention:  nats.LimitsPolicy,
+		Storage:    nats.FileStorage,
+		NoAck:      false,
+		Replicas:   1,
+		Duplicates: dedupe,
 	}
 	if err := ensureStream(js, eventsCfg); err != nil {
 		return err
 	}
 
 	decisionsCfg := &nats.StreamConfig{
-		Name:      StreamDecisions,
-		Subjects:  []string{SubjDecisionProv, SubjDecisionFinal, SubjDecisionOverride},
-		Retention: nats.LimitsPolicy,
-		Storage:   nats.FileStorage,
-		NoAck:     false,
-		Replicas:  1,
+		Name: StreamDecisions,
+		Subjects: []string{
+			SubjDecisionProv + ".>", SubjDecisionFinal + ".>", SubjDecisionOverride + ".>",
+			// unsuffixed subjects of earlier versions, e.g. records still in a gateway outbox
+			SubjDecisionProv, SubjDecisionFinal, SubjDecisionOverride,
+		},
+		Retention:  nats.LimitsPolicy,
+		Storage:    nats.FileStorage,
+		NoAck:      false,
+		Replicas:   1,
+		Duplicates: dedupe,
 	}
 	if err := ensureStream(js, decisionsCfg); err != nil {
 		return err
@@ -97,9 +142,66 @@ func Bootstrap(js nats.JetStreamContext) error {
 		return err
 	}
 
+	dlqCfg := &nats.StreamConfig{
+		Name:      StreamWebhookDLQ,
+		Subjects:  []string{SubjWebhookDLQ + ".>"},
+		Retention: nats.LimitsPolicy,
+		Storage:   nats.FileStorage,
+		NoAck:     false,
+		Replicas:  1,
+	}
+	if err := ensureStream(js, dlqCfg); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// ObjectStore binds to the named object store bucket, creating it if missing.
+func ObjectStore(js nats.JetStreamContext, bucket string) (nats.ObjectStore, error) {
+	obs, err := js.ObjectStore(bucket)
+	if err == nil {
+		return obs, nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return nil, fmt.Errorf("object store %s: %w", bucket, err)
+	}
+	obs, err = js.CreateObjectStore(&nats.ObjectStoreConfig{Bucket: bucket, Storage: nats.FileStorage, Replicas: 1})
+	if err != nil {
+		return nil, fmt.Errorf("create object store %s: %w", bucket, err)
+	}
+	return obs, nil
+}
+
+// KeyValue binds to the named key-value bucket, creating it if missing.
+// Entries expire after ttl; an existing bucket is brought to ttl.
+func KeyValue(js nats.JetStreamContext, bucket string, ttl time.Duration) (nats.KeyValue, error) {
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket, TTL: ttl, Storage: nats.FileStorage, Replicas: 1})
+		if err != nil {
+			return nil, fmt.Errorf("create key-value %s: %w", bucket, err)
+		}
+		return kv, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key-value %s: %w", bucket, err)
+	}
+	si, err := js.StreamInfo("KV_" + bucket)
+	if err != nil {
+		return nil, fmt.Errorf("key-value %s: %w", bucket, err)
+	}
+	if si.Config.MaxAge != ttl {
+		cfg := si.Config
+		cfg.MaxAge = ttl
+		cfg.Duplicates = min(cfg.Duplicates, ttl)
+		if _, err = js.UpdateStream(&cfg); err != nil {
+			return nil, fmt.Errorf("key-value %s ttl: %w", bucket, err)
+		}
+	}
+	return kv, nil
+}
+
 // SubscribeEphemeral wraps nc.Subscribe and cancels on ctx.Done().
 // Use for core NATS (non-JetStream) subjects.
 func SubscribeEphemeral(ctx context.Context, nc *nats.Conn, subj string, cb nats.MsgHandler) (*nats.Subscription, error) {
@@ -115,6 +217,20 @@ func SubscribeEphemeral(ctx context.Context, nc *nats.Conn, subj string, cb nats
 	return sub, nil
 }
 
+// SubscribeQueue is SubscribeEphemeral in a queue group, so each message is
+// handled by one member of the group.
+func SubscribeQueue(ctx context.Context, nc *nats.Conn, subj, queue string, cb nats.MsgHandler) (*nats.Subscription, error) {
+	sub, err := nc.QueueSubscribe(subj, queue, cb)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = sub.Drain()
+	}()
+	return sub, nil
+}
+
 // SubscribeDurable creates or attaches to a durable JetStream consumer and
 // drains it when ctx cancels. Handler is called for each message; handler
 // must Ack or Nak (we auto-Ack after handler returns if autoAck==true).
@@ -156,31 +272,133 @@ func SubscribeDurable(
 	return sub, nil
 }
 
-// EnsureDurableConsumer (helper) creates or updates a durable pull consumer.
-// Use from gateway/streamer services so each has its own durable cursor.
-// TODO
+// EnsureDurableConsumer creates or updates a durable push consumer on stream,
+// delivering to a subject derived from its name with queue group durable so
+// service replicas share it. A new consumer starts with new messages; an
+// existing one resumes from its ack floor. Unlike a consumer created by
+// SubscribeDurable, it outlives its subscriptions, so unacked messages are
+// redelivered after a restart. Bind to it with SubscribeBound.
 func EnsureDurableConsumer(js nats.JetStreamContext, stream, durable, filter string, ackWait time.Duration, maxAckPending int) error {
 	cfg := &nats.ConsumerConfig{
 		Durable:           durable,
+		DeliverSubject:    deliverPrefix + durable,
+		DeliverGroup:      durable,
 		AckPolicy:         nats.AckExplicitPolicy,
 		AckWait:           ackWait,
 		MaxAckPending:     maxAckPending,
 		FilterSubject:     filter, // empty => all subjects in stream
 		ReplayPolicy:      nats.ReplayInstantPolicy,
-		DeliverPolicy:     nats.DeliverAllPolicy,
+		DeliverPolicy:     nats.DeliverNewPolicy,
 		InactiveThreshold: 0,
 	}
+	if ci, err := js.ConsumerInfo(stream, durable); err == nil {
+		// the start position cannot be updated; keep the existing one
+		cfg.DeliverPolicy, cfg.OptStartSeq = ci.Config.DeliverPolicy, ci.Config.OptStartSeq
+		if ci.Config.DeliverSubject != cfg.DeliverSubject || ci.Config.DeliverGroup != cfg.DeliverGroup {
+			// made by a subscription: a push consumer does not move to a new
+			// deliver subject in place, so recreate it after its ack floor
+			if err := js.DeleteConsumer(stream, durable); err != nil {
+				return fmt.Errorf("recreate consumer %s on %s: %w", durable, stream, err)
+			}
+			if ci.AckFloor.Stream > 0 {
+				cfg.DeliverPolicy, cfg.OptStartSeq = nats.DeliverByStartSequencePolicy, ci.AckFloor.Stream+1
+			}
+		}
+	}
 	return ensureConsumer(js, stream, cfg)
 }
 
-// PublishJSON marshals v and publishes to subj via JetStream.
-// TODO for using durable publishing
-func PublishJSON(js nats.JetStreamContext, subj string, v any) (*nats.PubAck, error) {
-	b, err := json.Marshal(v)
+// deliverPrefix prefixes the deliver subjects of consumers made by
+// EnsureDurableConsumer; it must not overlap any stream's subjects.
+const deliverPrefix = "riskr.deliver."
+
+// SubscribeBound binds to the durable consumer on stream (see
+// EnsureDurableConsumer) and calls cb for each message; cb must ack. subj must
+// be the consumer's filter subject. The subscription drains when ctx ends and
+// the consumer is kept.
+func SubscribeBound(ctx context.Context, js nats.JetStreamContext, stream, durable, subj string, cb func(msg *nats.Msg)) (*nats.Subscription, error) {
+	sub, err := js.QueueSubscribe(subj, durable, cb, nats.Bind(stream, durable), nats.ManualAck())
 	if err != nil {
 		return nil, err
 	}
-	return js.Publish(subj, b)
+	go func() {
+		<-ctx.Done()
+		_ = sub.Drain()
+	}()
+	return sub, nil
+}
+
+// Publish retry and ack settings.
+const (
+	PublishAttempts = 3
+	PublishBackoff  = 50 * time.Millisecond
+	PublishAckWait  = 2 * time.Second
+)
+
+// ErrAckTimeout is returned when a stream does not ack a publish in time.
+var ErrAckTimeout = errors.New("jetstream publish ack timeout")
+
+// Publish sends msgs in order with JetStream async publishing and waits for
+// each stream ack, retrying from the first unacked message with backoff. Each
+// message should carry a Nats-Msg-Id header so a retry of a message that was
+// stored (but whose ack was lost) is dropped by the stream. It returns how many
+// leading messages were acked and, if not all, the last error.
+func Publish(ctx context.Context, js nats.JetStreamContext, msgs ...*nats.Msg) (int, error) {
+	acked, backoff := 0, PublishBackoff
+	for attempt := 1; ; attempt++ {
+		n, err := publishAsync(ctx, js, msgs[acked:])
+		acked += n
+		if err == nil {
+			return acked, nil
+		}
+		if attempt == PublishAttempts {
+			return acked, err
+		}
+		select {
+		case <-ctx.Done():
+			return acked, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func publishAsync(ctx context.Context, js nats.JetStreamContext, msgs []*nats.Msg) (int, error) {
+	futs := make([]nats.PubAckFuture, 0, len(msgs))
+	var pubErr error
+	for _, m := range msgs {
+		f, err := js.PublishMsgAsync(m)
+		if err != nil {
+			pubErr = err
+			break
+		}
+		futs = append(futs, f)
+	}
+	timeout := time.NewTimer(PublishAckWait)
+	defer timeout.Stop()
+	for i, f := range futs {
+		select {
+		case <-f.Ok():
+		case err := <-f.Err():
+			return i, err
+		case <-timeout.C:
+			return i, ErrAckTimeout
+		case <-ctx.Done():
+			return i, ctx.Err()
+		}
+	}
+	return len(futs), pubErr
+}
+
+// PublishJSON marshals v and publishes it to subj via JetStream with msgID
+// for deduplication, waiting for the stream ack.
+func PublishJSON(ctx context.Context, js nats.JetStreamContext, subj, msgID string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = Publish(ctx, js, &nats.Msg{Subject: subj, Data: b, Header: nats.Header{nats.MsgIdHdr: []string{msgID}}})
+	return err
 }
 
 // -----------------------------------------------------------------------------
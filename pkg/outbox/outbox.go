@@ -0,0 +1,396 @@
+// Package outbox is a local write-ahead queue for messages that could not be
+// published. While the broker is unreachable, messages are appended to a file
+// on disk; a replay loop publishes them in order once it is reachable again.
+// New messages queue behind buffered ones so per-producer order is kept; the
+// records of one Publish stay in order, concurrent Publishes are unordered.
+//
+// Replay is at-least-once: a crash between a publish and the cursor update
+// republishes that message, so records should carry a message ID for broker
+// side deduplication.
+package outbox
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/log"
+)
+
+// Record is one buffered message.
+type Record struct {
+	Subject string    `json:"subject"`
+	MsgID   string    `json:"msg_id,omitempty"`
+	Data    []byte    `json:"data"`
+	At      time.Time `json:"at"` // when it was first offered
+}
+
+// PublishFunc delivers records in order until ctx ends and reports how many
+// leading records were delivered; the rest stay in (or go to) the outbox.
+type PublishFunc func(ctx context.Context, recs []Record) (int, error)
+
+// ErrFull is returned by Publish when records had to be buffered and the
+// outbox has no room for them.
+var ErrFull = errors.New("outbox full")
+
+const (
+	walFile     = "outbox.wal"
+	posFile     = "outbox.pos"
+	headerSize  = 8 // uint32 length + uint32 crc32 of the payload
+	retryEvery  = time.Second
+	replayBatch = 128
+
+	// DefaultReplayTimeout bounds the publish of one replay batch.
+	DefaultReplayTimeout = 10 * time.Second
+)
+
+// Stats is a point-in-time view of the outbox, reported on /status.
+type Stats struct {
+	Pending  int        `json:"pending"` // records waiting for replay
+	Bytes    int64      `json:"bytes"`   // on-disk size of pending records
+	MaxBytes int64      `json:"max_bytes"`
+	Full     bool       `json:"full"` // last Publish was refused for lack of room
+	OldestAt *time.Time `json:"oldest_at,omitempty"`
+	Enqueued uint64     `json:"enqueued"` // records buffered since start
+	Replayed uint64     `json:"replayed"` // buffered records published since start
+	Refused  uint64     `json:"refused"`  // records refused because the outbox was full
+}
+
+type Outbox struct {
+	// ReplayTimeout bounds each replay batch publish; set before Run.
+	ReplayTimeout time.Duration
+
+	// mu guards the file and counters; it is held over disk I/O but never
+	// over a publish
+	mu       sync.Mutex
+	f        *os.File
+	walPath  string
+	posPath  string
+	pos      int64 // offset of the first pending record
+	size     int64 // end of the last complete record
+	pending  int
+	oldest   time.Time
+	maxBytes int64
+	full     bool
+	enqueued uint64
+	replayed uint64
+	refused  uint64
+
+	pub  PublishFunc
+	log  log.Logger
+	kick chan struct{}
+}
+
+// Open opens (or creates) the outbox in dir. Records left from a previous run
+// are kept and replayed; a torn record at the end of the file is dropped.
+func Open(dir string, maxBytes int64, pub PublishFunc, logger log.Logger) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	o := &Outbox{ReplayTimeout: DefaultReplayTimeout, f: f, walPath: filepath.Join(dir, walFile), posPath: filepath.Join(dir, posFile), maxBytes: maxBytes, pub: pub, log: logger, kick: make(chan struct{}, 1)}
+	if b, err := os.ReadFile(o.posPath); err == nil {
+		o.pos, _ = strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	}
+	if err := o.recover(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if o.pending > 0 {
+		logger.Warn("outbox has pending records", "pending", o.pending, "bytes", o.size-o.pos)
+	}
+	return o, nil
+}
+
+// recover scans the pending region, counting records and truncating any
+// incomplete or corrupt tail.
+func (o *Outbox) recover() error {
+	fi, err := o.f.Stat()
+	if err != nil {
+		return err
+	}
+	if o.pos < 0 || o.pos > fi.Size() {
+		o.pos = 0
+	}
+	off := o.pos
+	for {
+		rec, n, err := o.readAt(off)
+		if err != nil {
+			break
+		}
+		if o.pending == 0 {
+			o.oldest = rec.At
+		}
+		o.pending++
+		off += n
+	}
+	if off < fi.Size() {
+		o.log.Warn("outbox dropping torn tail", "offset", off, "size", fi.Size())
+		if err := o.f.Truncate(off); err != nil {
+			return err
+		}
+	}
+	o.size = off
+	return nil
+}
+
+func (o *Outbox) readAt(off int64) (Record, int64, error) {
+	var hdr [headerSize]byte
+	if _, err := o.f.ReadAt(hdr[:], off); err != nil {
+		return Record{}, 0, err
+	}
+	n := binary.BigEndian.Uint32(hdr[0:4])
+	buf := make([]byte, n)
+	if _, err := o.f.ReadAt(buf, off+headerSize); err != nil {
+		return Record{}, 0, err
+	}
+	if crc32.ChecksumIEEE(buf) != binary.BigEndian.Uint32(hdr[4:8]) {
+		return Record{}, 0, fmt.Errorf("outbox: bad checksum at %d", off)
+	}
+	var rec Record
+	if err := json.Unmarshal(buf, &rec); err != nil {
+		return Record{}, 0, err
+	}
+	return rec, headerSize + int64(n), nil
+}
+
+// Publish delivers recs in order. When the outbox is empty they are published
+// directly until ctx ends; from the first failure or timeout on (or if older
+// records are still pending) they are appended to disk for replay, behind
+// the records of every Publish that buffered before. Direct publishes run
+// concurrently. ErrFull means the remaining records were neither delivered
+// nor buffered.
+func (o *Outbox) Publish(ctx context.Context, recs ...Record) error {
+	now := time.Now()
+	for i := range recs {
+		if recs[i].At.IsZero() {
+			recs[i].At = now
+		}
+	}
+	o.mu.Lock()
+	direct := o.pending == 0
+	o.mu.Unlock()
+	if direct {
+		n, err := o.pub(ctx, recs)
+		if err == nil {
+			return nil
+		}
+		recs = recs[n:]
+		o.log.Warn("publish failed, buffering in outbox", "subject", recs[0].Subject, "records", len(recs), "err", err)
+	}
+	return o.append(recs)
+}
+
+func (o *Outbox) append(recs []Record) error {
+	var buf []byte
+	for _, r := range recs {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		var hdr [headerSize]byte
+		binary.BigEndian.PutUint32(hdr[0:4], uint32(len(b)))
+		binary.BigEndian.PutUint32(hdr[4:8], crc32.ChecksumIEEE(b))
+		buf = append(append(buf, hdr[:]...), b...)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.maxBytes > 0 && o.size-o.pos+int64(len(buf)) > o.maxBytes {
+		o.full = true
+		o.refused += uint64(len(recs))
+		return ErrFull
+	}
+	if o.maxBytes > 0 && o.size+int64(len(buf)) > o.maxBytes {
+		// room once the replayed head is dropped
+		if err := o.compactLocked(); err != nil {
+			return err
+		}
+	}
+	if _, err := o.f.WriteAt(buf, o.size); err != nil {
+		return err
+	}
+	if err := o.f.Sync(); err != nil {
+		return err
+	}
+	if o.pending == 0 {
+		o.oldest = recs[0].At
+	}
+	o.size += int64(len(buf))
+	o.pending += len(recs)
+	o.enqueued += uint64(len(recs))
+	o.full = false
+	o.signal()
+	return nil
+}
+
+// Kick triggers a replay attempt, e.g. on reconnect.
+func (o *Outbox) Kick() { o.signal() }
+
+func (o *Outbox) signal() {
+	select {
+	case o.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Run replays pending records until ctx ends, on Kick and periodically.
+func (o *Outbox) Run(ctx context.Context) {
+	t := time.NewTicker(retryEvery)
+	defer t.Stop()
+	defer func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.f.Close()
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.kick:
+		case <-t.C:
+		}
+		o.replay(ctx)
+	}
+}
+
+// replay publishes pending records in order and stops at the first failure.
+// Each batch is read under the lock and published without it; only replay
+// advances pos, so the batch is still at pos afterwards (compaction moves
+// both).
+func (o *Outbox) replay(ctx context.Context) {
+	n := 0
+	for ctx.Err() == nil {
+		o.mu.Lock()
+		batch, sizes := o.readBatchLocked()
+		o.mu.Unlock()
+		if len(batch) == 0 {
+			break
+		}
+		pctx, cancel := context.WithTimeout(ctx, o.ReplayTimeout)
+		sent, err := o.pub(pctx, batch)
+		cancel()
+		n += sent
+		o.mu.Lock()
+		for _, sz := range sizes[:sent] {
+			o.pos += sz
+		}
+		o.pending -= sent
+		o.replayed += uint64(sent)
+		if next, _, rerr := o.readAt(o.pos); rerr == nil {
+			o.oldest = next.At
+		}
+		o.mu.Unlock()
+		if err != nil {
+			break
+		}
+	}
+	if n == 0 {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch {
+	case o.pos == o.size:
+		// drained: start the file over
+		if err := o.f.Truncate(0); err != nil {
+			o.log.Error("outbox truncate", "err", err)
+		} else {
+			o.pos, o.size = 0, 0
+		}
+		o.full = false
+	case o.pos >= o.size-o.pos:
+		// mostly replayed: copying the rest is cheaper than what it frees
+		if err := o.compactLocked(); err != nil {
+			o.log.Error("outbox compact", "err", err)
+		}
+	}
+	if err := o.savePos(); err != nil {
+		o.log.Error("outbox cursor", "err", err)
+	}
+	o.log.Info("outbox replayed", "records", n, "pending", o.pending)
+}
+
+// readBatchLocked reads up to replayBatch records from pos.
+func (o *Outbox) readBatchLocked() ([]Record, []int64) {
+	var batch []Record
+	var sizes []int64
+	for next := o.pos; next < o.size && len(batch) < replayBatch; {
+		rec, sz, err := o.readAt(next)
+		if err != nil {
+			o.log.Error("outbox read", "offset", next, "err", err)
+			break
+		}
+		batch, sizes = append(batch, rec), append(sizes, sz)
+		next += sz
+	}
+	return batch, sizes
+}
+
+// compactLocked rewrites the file with only the pending records. The cursor
+// is reset before the rename, so a crash in between replays the old file
+// from the start (duplicates, not loss).
+func (o *Outbox) compactLocked() error {
+	head := o.pos
+	if head == 0 {
+		return nil
+	}
+	tmp := o.walPath + ".tmp"
+	nf, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(nf, io.NewSectionReader(o.f, head, o.size-head)); err == nil {
+		err = nf.Sync()
+	}
+	if err == nil {
+		o.pos = 0
+		if err = o.savePos(); err == nil {
+			err = os.Rename(tmp, o.walPath)
+		}
+	}
+	if err != nil {
+		// keep the old file; a cursor saved as 0 only replays it from the start
+		o.pos = head
+		nf.Close()
+		os.Remove(tmp)
+		return err
+	}
+	o.f.Close()
+	o.f = nf
+	o.size -= head
+	return nil
+}
+
+// savePos persists the replay cursor; it is written to a temp file and
+// renamed so a crash leaves either the old or the new value.
+func (o *Outbox) savePos() error {
+	tmp := o.posPath + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(o.pos, 10)), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.posPath)
+}
+
+func (o *Outbox) Stats() Stats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	st := Stats{Pending: o.pending, Bytes: o.size - o.pos, MaxBytes: o.maxBytes, Full: o.full, Enqueued: o.enqueued, Replayed: o.replayed, Refused: o.refused}
+	if o.pending > 0 {
+		at := o.oldest
+		st.OldestAt = &at
+	}
+	return st
+}
@@ -0,0 +1,189 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...any) {}
+func (nopLogger) Info(string, ...any)  {}
+func (nopLogger) Warn(string, ...any)  {}
+func (nopLogger) Error(string, ...any) {}
+
+// broker is a fake PublishFunc target; hook, if set, runs before each publish
+// and its error fails it.
+type broker struct {
+	mu   sync.Mutex
+	got  []string
+	hook func(recs []Record) error
+}
+
+func (b *broker) publish(_ context.Context, recs []Record) (int, error) {
+	if b.hook != nil {
+		if err := b.hook(recs); err != nil {
+			return 0, err
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, r := range recs {
+		b.got = append(b.got, r.MsgID)
+	}
+	return len(recs), nil
+}
+
+// A publish waiting on the broker must not hold up other publishes, and one
+// made after a publish fell back to the outbox must queue behind it.
+func TestPublishKeepsOrderWhileFallingBack(t *testing.T) {
+	b := &broker{}
+	o, err := Open(t.TempDir(), 0, b.publish, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inA, failA := make(chan struct{}), make(chan struct{})
+	down := true
+	b.hook = func(recs []Record) error {
+		if recs[0].MsgID == "a" && down {
+			close(inA)
+			<-failA
+			return errors.New("broker down")
+		}
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := o.Publish(context.Background(), Record{Subject: "s", MsgID: "a"}); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-inA
+	// b goes straight through while a is still waiting
+	if err := o.Publish(context.Background(), Record{Subject: "s", MsgID: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	close(failA)
+	<-done
+	if err := o.Publish(context.Background(), Record{Subject: "s", MsgID: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	down = false
+	o.replay(context.Background())
+	if want := []string{"b", "a", "c"}; !slices.Equal(b.got, want) {
+		t.Fatalf("broker got %v, want %v", b.got, want)
+	}
+	if st := o.Stats(); st.Pending != 0 || st.Replayed != 2 {
+		t.Fatalf("stats = %+v, want drained", st)
+	}
+}
+
+func TestReplayBoundsEachPublish(t *testing.T) {
+	b := &broker{hook: func([]Record) error { return errors.New("broker down") }}
+	o, err := Open(t.TempDir(), 0, b.publish, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Publish(context.Background(), Record{Subject: "s", MsgID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	// connected but never acking: the publish only ends with its ctx
+	o.pub = func(ctx context.Context, _ []Record) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	o.ReplayTimeout = 20 * time.Millisecond
+	start := time.Now()
+	o.replay(context.Background())
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("replay took %v", d)
+	}
+	if st := o.Stats(); st.Pending != 1 {
+		t.Fatalf("stats = %+v, want the record kept", st)
+	}
+}
+
+// A partial drain compacts the file, so it stays within maxBytes on disk and
+// the pending records survive a reopen in order.
+func TestReplayCompactsWithinMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	b := &broker{}
+	down := true
+	b.hook = func([]Record) error {
+		if down {
+			return errors.New("broker down")
+		}
+		return nil
+	}
+	rec := func(id string) Record {
+		return Record{Subject: "s", MsgID: id, Data: make([]byte, 100), At: time.Unix(1, 0)}
+	}
+	o, err := Open(dir, 0, b.publish, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if err := o.Publish(context.Background(), rec(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	size := o.Stats().Bytes
+	o.maxBytes = size // room for four records
+
+	// the broker takes three, then fails
+	o.pub = func(ctx context.Context, recs []Record) (int, error) {
+		n, _ := b.publish(ctx, recs[:3])
+		return n, errors.New("broker down")
+	}
+	down = false
+	o.replay(context.Background())
+	// three more only fit once the replayed head is gone
+	o.pub = b.publish
+	down = true
+	for _, id := range []string{"e", "f", "g"} {
+		if err := o.Publish(context.Background(), rec(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fi, err := os.Stat(filepath.Join(dir, walFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() > size {
+		t.Fatalf("wal is %d bytes, max %d", fi.Size(), size)
+	}
+
+	o2, err := Open(dir, size, b.publish, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	down = false
+	o2.replay(context.Background())
+	if want := []string{"a", "b", "c", "d", "e", "f", "g"}; !slices.Equal(b.got, want) {
+		t.Fatalf("broker got %v, want %v", b.got, want)
+	}
+}
+
+func TestPublishFullRefuses(t *testing.T) {
+	b := &broker{hook: func([]Record) error { return errors.New("broker down") }}
+	o, err := Open(t.TempDir(), 64, b.publish, nopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := Record{Subject: "s", MsgID: "x", Data: make([]byte, 64)}
+	if err := o.Publish(context.Background(), big); !errors.Is(err, ErrFull) {
+		t.Fatalf("Publish = %v, want ErrFull", err)
+	}
+	if st := o.Stats(); !st.Full || st.Refused != 1 || st.Pending != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
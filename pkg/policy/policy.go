@@ -16,11 +16,14 @@ import (
 )
 
 type Policy struct {
-	Version string         `yaml:"policy_version" json:"policy_version"`
-	Params  map[string]any `yaml:"params" json:"params"`
-	Rules   []RuleDef      `yaml:"rules" json:"rules"`
-	Sig     string         `yaml:"signature" json:"signature"`
-	Hash    string         `yaml:"-" json:"hash"`
+	Version   string            `yaml:"policy_version" json:"policy_version"`
+	Params    map[string]any    `yaml:"params" json:"params"`
+	Rules     []RuleDef         `yaml:"rules" json:"rules"`
+	Lists     []ListDef         `yaml:"screening_lists" json:"screening_lists,omitempty"`
+	TTLs      map[string]string `yaml:"decision_ttls" json:"decision_ttls,omitempty"` // re-check after, by decision type (e.g. HOLD_AUTO: 15m)
+	Fallbacks map[string]string `yaml:"fallbacks" json:"fallbacks,omitempty"`         // decision for rules not evaluated, by rule class; unset = rule action
+	Sig       string            `yaml:"signature" json:"signature"`
+	Hash      string            `yaml:"-" json:"hash"`
 }
 
 type RuleDef struct {
@@ -28,6 +31,21 @@ type RuleDef struct {
 	Type             string   `yaml:"type" json:"type"`
 	Action           string   `yaml:"action" json:"action"`
 	BlockedCountries []string `yaml:"blocked_countries" json:"blocked_countries,omitempty"`
+	Direction        string   `yaml:"direction" json:"direction,omitempty"` // inbound|outbound, empty = both
+	Lists            []string `yaml:"lists" json:"lists,omitempty"`         // screening lists to check, empty = all
+	TTL              string   `yaml:"ttl" json:"ttl,omitempty"`             // decision TTL when this rule hits; overrides decision_ttls
+}
+
+// ListDef declares a named screening list. Source is a file path, relative to
+// the service config root; Version optionally pins the expected list version
+// (its `# version:` header, or content hash) and rejects any other. Names
+// optionally points at the list's party names file for name screening.
+type ListDef struct {
+	Name    string `yaml:"name" json:"name"`
+	Source  string `yaml:"source" json:"source"`
+	Version string `yaml:"version" json:"version,omitempty"`
+	Action  string `yaml:"action" json:"action,omitempty"` // decision on hit, empty = rule action
+	Names   string `yaml:"names" json:"names,omitempty"`
 }
 
 func LoadFile(path string) (*Policy, error) {
@@ -0,0 +1,279 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/christophercampbell/riskr/pkg/decision"
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+	"github.com/christophercampbell/riskr/pkg/state"
+)
+
+// Rule classes, used to pick a fallback decision (policy `fallbacks`) for
+// rules that could not be evaluated.
+const (
+	ClassSanctions    = "sanctions"
+	ClassJurisdiction = "jurisdiction"
+	ClassLimits       = "limits"
+	ClassBehavior     = "behavior"
+)
+
+var ruleClass = map[string]string{
+	"ofac_addr":               ClassSanctions,
+	"sanctions_exposure":      ClassSanctions,
+	"name_screening":          ClassSanctions,
+	"jurisdiction_block":      ClassJurisdiction,
+	"kyc_tier_tx_cap":         ClassLimits,
+	"daily_usd_volume":        ClassLimits,
+	"structuring_small_tx":    ClassBehavior,
+	"pass_through":            ClassBehavior,
+	"counterparty_dispersion": ClassBehavior,
+	"new_destination_address": ClassBehavior,
+	"threshold_proximity":     ClassBehavior,
+}
+
+// Degradation reasons.
+const (
+	DegradedBudget     = "latency_budget"
+	DegradedDependency = "dependency_unavailable"
+)
+
+// Mode is a set of evaluation modes.
+type Mode uint8
+
+const (
+	ModeInline Mode = 1 << iota
+	ModeStreaming
+)
+
+// Moded is implemented by rules that apply in only some modes, e.g. the
+// state-based rules, which are no-ops inline. Other rules apply in both.
+type Moded interface {
+	Modes() Mode
+}
+
+func modes(r Rule) Mode {
+	if m, ok := r.(Moded); ok {
+		return m.Modes()
+	}
+	return ModeInline | ModeStreaming
+}
+
+// Dependent is implemented by rules relying on per-event data that may be
+// missing (the USD price). Ready reports why the rule cannot be evaluated for
+// e, or nil. Screening lists and party names are checked when the snapshot is
+// compiled, and the state store is in-process, so neither can go missing
+// between events.
+type Dependent interface {
+	Ready(e *events.TxEvent) error
+}
+
+// ctxRule is implemented by rules whose streaming evaluation can run long
+// (graph walks); they stop early when ctx ends.
+type ctxRule interface {
+	evalStreamingCtx(ctx context.Context, now time.Time, e *events.TxEvent, st state.View) (bool, string, events.Evidence)
+}
+
+// inlineStateRule is implemented by state-based rules that also apply inline,
+// against the caller's own state (the gateway's record of the destinations
+// it checked).
+type inlineStateRule interface {
+	evalInlineState(now time.Time, e *events.TxEvent, st state.View) (bool, string, events.Evidence)
+}
+
+// DegradedEvidence is the evidence value for a rule that was not evaluated
+// and contributed its class fallback instead.
+type DegradedEvidence struct {
+	Class    string `json:"class"`
+	Reason   string `json:"reason"`
+	Detail   string `json:"detail,omitempty"`
+	Fallback string `json:"fallback"`
+}
+
+// Outcome is the combined result of a snapshot's rules for one event.
+type Outcome struct {
+	Decision string
+	Evidence []events.Evidence
+	Degraded bool // at least one rule used its fallback
+}
+
+// EvalInline evaluates the inline rules for e until ctx ends. st is the
+// caller's state for the rules that use it inline; with nil they see none.
+func (s *Snapshot) EvalInline(ctx context.Context, e *events.TxEvent, st state.View) Outcome {
+	return s.eval(ctx, ModeInline, e, func(_ context.Context, r Rule) (bool, string, events.Evidence) {
+		if sr, ok := r.(inlineStateRule); ok && st != nil {
+			return sr.evalInlineState(time.Now(), e, st)
+		}
+		return r.EvalInline(e)
+	})
+}
+
+// EvalStreaming evaluates the streaming rules for e until ctx ends.
+func (s *Snapshot) EvalStreaming(ctx context.Context, now time.Time, e *events.TxEvent, st state.View) Outcome {
+	return s.eval(ctx, ModeStreaming, e, func(ctx context.Context, r Rule) (bool, string, events.Evidence) {
+		if cr, ok := r.(ctxRule); ok {
+			return cr.evalStreamingCtx(ctx, now, e, st)
+		}
+		return r.EvalStreaming(now, e, st)
+	})
+}
+
+type ruleResult struct {
+	i   int
+	hit bool
+	dec string
+	ev  events.Evidence
+}
+
+// eval runs the rules applicable to mode concurrently, so a slow rule neither
+// holds the caller past ctx's deadline nor keeps later rules from running.
+// Rules still running at the deadline contribute their class fallback decision
+// and degraded evidence; their results are discarded, and long-running rules
+// stop on ctx. Rules whose dependencies are unavailable for e degrade too.
+//
+// Streaming evaluation of a gateway check (Chain INLINE) does not degrade rules
+// that also run inline: the gateway already applied their fallback.
+func (s *Snapshot) eval(ctx context.Context, mode Mode, e *events.TxEvent, fn func(context.Context, Rule) (bool, string, events.Evidence)) Outcome {
+	out := Outcome{Decision: decision.Allow}
+	rechecked := mode == ModeStreaming && e.Chain == events.ChainInline
+	quiet := func(r Rule) bool { return rechecked && modes(r)&ModeInline != 0 }
+
+	var degraded []events.Evidence
+	results := make(chan ruleResult, len(s.Rules)) // buffered: late rules never block
+	started := make(map[int]bool, len(s.Rules))
+	for i, r := range s.Rules {
+		if modes(r)&mode == 0 {
+			continue
+		}
+		if d, ok := r.(Dependent); ok {
+			if err := d.Ready(e); err != nil {
+				if !quiet(r) {
+					degraded = append(degraded, s.degrade(&out, r.ID(), DegradedDependency, err.Error()))
+				}
+				continue
+			}
+		}
+		started[i] = true
+		go func() {
+			hit, dec, ev := fn(ctx, r)
+			results <- ruleResult{i: i, hit: hit, dec: dec, ev: ev}
+		}()
+	}
+
+	done := make([]*ruleResult, len(s.Rules))
+collect:
+	for range started {
+		select {
+		case res := <-results:
+			done[res.i] = &res
+		case <-ctx.Done():
+			break collect
+		}
+	}
+	var budget []events.Evidence
+	for i, r := range s.Rules {
+		res := done[i]
+		switch {
+		case res == nil && started[i] && !quiet(r):
+			budget = append(budget, s.degrade(&out, r.ID(), DegradedBudget, ""))
+		case res != nil && res.hit:
+			out.Decision = decision.Max(out.Decision, res.dec)
+			if res.ev.RuleID != "" {
+				out.Evidence = append(out.Evidence, res.ev)
+			}
+		}
+	}
+	// regular evidence first so the decision code names a rule that actually hit
+	out.Evidence = append(append(out.Evidence, degraded...), budget...)
+	return out
+}
+
+func (s *Snapshot) degrade(out *Outcome, ruleID, reason, detail string) events.Evidence {
+	rd := s.defs[ruleID]
+	class := ruleClass[rd.Type]
+	fb := s.Policy.Fallbacks[class]
+	if fb == "" {
+		fb = rd.Action // fail closed unless the policy says otherwise
+	}
+	out.Decision = decision.Max(out.Decision, fb)
+	out.Degraded = true
+	return events.Evidence{RuleID: ruleID, Key: "degraded", Value: DegradedEvidence{Class: class, Reason: reason, Detail: detail, Fallback: fb}, Degraded: true}
+}
+
+// checkLists reports screening list misconfiguration of p against set: rules
+// naming lists the set lacks, and name screening with no party names to
+// screen against. Such rules could never be evaluated, so the snapshot is
+// rejected rather than degrading every event.
+func checkLists(p *policy.Policy, set *sanctions.Set) error {
+	if set == nil || len(set.Lists()) == 0 {
+		return fmt.Errorf("no screening lists loaded")
+	}
+	for _, rd := range p.Rules {
+		if ruleClass[rd.Type] != ClassSanctions {
+			continue
+		}
+		for _, n := range rd.Lists {
+			if set.Get(n) == nil {
+				return fmt.Errorf("rule %s: screening list %s not loaded", rd.ID, n)
+			}
+		}
+		if rd.Type != "name_screening" {
+			continue
+		}
+		named := false
+		for _, l := range set.Lists() {
+			named = named || (l.Names != nil && (len(rd.Lists) == 0 || slices.Contains(rd.Lists, l.Name)))
+		}
+		if !named {
+			return fmt.Errorf("rule %s: no party names loaded for name screening", rd.ID)
+		}
+	}
+	return nil
+}
+
+// checkParams reports params of p that are out of range for the rules using them.
+func checkParams(p *policy.Policy) error {
+	for _, rd := range p.Rules {
+		if rd.Type != "threshold_proximity" {
+			continue
+		}
+		if v, ok := p.Params["threshold_proximity_pct"]; ok {
+			if pct := toDec(v); !pct.IsPositive() || pct.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+				return fmt.Errorf("rule %s: threshold_proximity_pct %v not in (0, 100)", rd.ID, v)
+			}
+		}
+	}
+	return nil
+}
+
+// priceReady reports whether e carries a USD value.
+func priceReady(e *events.TxEvent) error {
+	if _, err := decimal.NewFromString(e.USDValue); err != nil {
+		return fmt.Errorf("no usd price for %s", e.Asset)
+	}
+	return nil
+}
+
+func (r *kycTierCapRule) Ready(e *events.TxEvent) error         { return priceReady(e) }
+func (r *dailyVolRule) Ready(e *events.TxEvent) error           { return priceReady(e) }
+func (r *thresholdProximityRule) Ready(e *events.TxEvent) error { return priceReady(e) }
+
+// only the first-use amount check needs a price
+func (r *newDestRule) Ready(e *events.TxEvent) error {
+	if !r.firstUseMax.IsPositive() {
+		return nil
+	}
+	return priceReady(e)
+}
+
+func (r *dailyVolRule) Modes() Mode    { return ModeStreaming }
+func (r *structuringRule) Modes() Mode { return ModeStreaming }
+func (r *passThroughRule) Modes() Mode { return ModeStreaming }
+func (r *dispersionRule) Modes() Mode  { return ModeStreaming }
+func (r *exposureRule) Modes() Mode    { return ModeStreaming }
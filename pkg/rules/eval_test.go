@@ -0,0 +1,87 @@
+package rules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/decision"
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/state"
+)
+
+// slowRule blocks until ctx ends or release is closed, then hits.
+type slowRule struct {
+	id      string
+	release chan struct{}
+}
+
+func (r *slowRule) ID() string { return r.id }
+func (r *slowRule) EvalInline(e *events.TxEvent) (bool, string, events.Evidence) {
+	<-r.release
+	return true, decision.RejectFatal, events.Evidence{RuleID: r.id}
+}
+func (r *slowRule) EvalStreaming(_ time.Time, e *events.TxEvent, _ state.View) (bool, string, events.Evidence) {
+	return r.EvalInline(e)
+}
+
+func testSnapshot(rules []Rule, defs ...policy.RuleDef) *Snapshot {
+	p := &policy.Policy{Version: "test", Rules: defs, Fallbacks: map[string]string{ClassSanctions: decision.HoldAuto, ClassLimits: decision.HoldAuto}}
+	s := &Snapshot{Policy: p, Version: p.Version, Rules: rules, defs: make(map[string]policy.RuleDef)}
+	for _, rd := range defs {
+		s.defs[rd.ID] = rd
+	}
+	return s
+}
+
+func degradedRules(out Outcome) map[string]string {
+	m := make(map[string]string)
+	for _, ev := range out.Evidence {
+		if ev.Degraded {
+			m[ev.RuleID] = ev.Value.(DegradedEvidence).Reason
+		}
+	}
+	return m
+}
+
+func TestEvalDegradesOnlyUnfinishedRules(t *testing.T) {
+	slow := &slowRule{id: "slow", release: make(chan struct{})}
+	defer close(slow.release)
+	defs := []policy.RuleDef{
+		{ID: "slow", Type: "ofac_addr", Action: decision.RejectFatal},
+		{ID: "vol", Type: "daily_usd_volume", Action: decision.HoldAuto},
+		{ID: "cap", Type: "kyc_tier_tx_cap", Action: decision.HoldAuto},
+	}
+	snap := testSnapshot([]Rule{slow, newDailyVolRule(defs[1], nil), newKYCTierCapRule(defs[2], nil)}, defs...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	out := snap.EvalInline(ctx, &events.TxEvent{USDValue: "10"}, nil)
+	got := degradedRules(out)
+	if len(got) != 1 || got["slow"] != DegradedBudget {
+		t.Fatalf("degraded = %v, want only slow by budget", got)
+	}
+	if out.Decision != decision.HoldAuto {
+		t.Fatalf("decision = %s, want sanctions fallback %s", out.Decision, decision.HoldAuto)
+	}
+}
+
+func TestEvalSkipsRulesOfOtherModes(t *testing.T) {
+	defs := []policy.RuleDef{
+		{ID: "vol", Type: "daily_usd_volume", Action: decision.HoldAuto},
+		{ID: "cap", Type: "kyc_tier_tx_cap", Action: decision.HoldAuto},
+	}
+	snap := testSnapshot([]Rule{newDailyVolRule(defs[0], nil), newKYCTierCapRule(defs[1], nil)}, defs...)
+
+	// no price: only the inline rule degrades inline
+	out := snap.EvalInline(context.Background(), &events.TxEvent{}, nil)
+	if got := degradedRules(out); len(got) != 1 || got["cap"] != DegradedDependency {
+		t.Fatalf("inline degraded = %v, want only cap", got)
+	}
+	// a gateway check re-evaluated by the streamer: inline rules were degraded already
+	out = snap.EvalStreaming(context.Background(), time.Now(), &events.TxEvent{Chain: events.ChainInline}, state.NewMem())
+	if got := degradedRules(out); len(got) != 1 || got["vol"] != DegradedDependency {
+		t.Fatalf("streaming degraded = %v, want only vol", got)
+	}
+}
@@ -1,14 +1,19 @@
 package rules
 
 import (
+	"context"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
 
+	"github.com/christophercampbell/riskr/pkg/address"
 	"github.com/christophercampbell/riskr/pkg/decision"
 	"github.com/christophercampbell/riskr/pkg/events"
 	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
 	"github.com/christophercampbell/riskr/pkg/state"
 )
 
@@ -18,13 +23,13 @@ type Rule interface {
 	EvalStreaming(now time.Time, e *events.TxEvent, st state.View) (hit bool, dec string, ev events.Evidence)
 }
 
-// BuildRules constructs rule instances from policy defs + params + sanctions & thresholds.
-func BuildRules(p *policy.Policy, sanctions map[string]struct{}, params map[string]any) []Rule {
+// BuildRules constructs rule instances from policy defs + params + screening lists & thresholds.
+func BuildRules(p *policy.Policy, lists *sanctions.Set, params map[string]any) []Rule {
 	r := make([]Rule, 0, len(p.Rules))
 	for _, rd := range p.Rules {
 		switch rd.Type {
 		case "ofac_addr":
-			r = append(r, newOFACRule(rd, sanctions))
+			r = append(r, newOFACRule(rd, lists))
 		case "jurisdiction_block":
 			r = append(r, newJurisRule(rd))
 		case "kyc_tier_tx_cap":
@@ -33,6 +38,18 @@ func BuildRules(p *policy.Policy, sanctions map[string]struct{}, params map[stri
 			r = append(r, newDailyVolRule(rd, params))
 		case "structuring_small_tx":
 			r = append(r, newStructuringRule(rd, params))
+		case "pass_through":
+			r = append(r, newPassThroughRule(rd, params))
+		case "counterparty_dispersion":
+			r = append(r, newDispersionRule(rd, params))
+		case "new_destination_address":
+			r = append(r, newNewDestRule(rd, params))
+		case "threshold_proximity":
+			r = append(r, newThresholdProximityRule(rd, params))
+		case "sanctions_exposure":
+			r = append(r, newExposureRule(rd, lists, params))
+		case "name_screening":
+			r = append(r, newNameScreeningRule(rd, lists, params))
 		}
 	}
 	return r
@@ -41,25 +58,36 @@ func BuildRules(p *policy.Policy, sanctions map[string]struct{}, params map[stri
 // ------------------------ OFAC Rule ------------------------
 
 type ofacRule struct {
-	id      string
-	action  string
-	addrSet map[string]struct{}
+	id     string
+	action string
+	lists  *sanctions.Set
+	names  []string // lists to screen against, empty = all
 }
 
-func newOFACRule(rd policy.RuleDef, addrs map[string]struct{}) Rule {
-	return &ofacRule{id: rd.ID, action: rd.Action, addrSet: addrs}
+func newOFACRule(rd policy.RuleDef, lists *sanctions.Set) Rule {
+	return &ofacRule{id: rd.ID, action: rd.Action, lists: lists, names: rd.Lists}
 }
 
 func (r *ofacRule) ID() string { return r.id }
 
 func (r *ofacRule) EvalInline(e *events.TxEvent) (bool, string, events.Evidence) {
 	// check any subject addr or tx direction address? For MVP we just check Subject.Addresses
+	hit, dec := false, decision.Allow
+	var ev events.Evidence
 	for _, a := range e.Subject.Addresses {
-		if _, ok := r.addrSet[strings.ToLower(a)]; ok {
-			return true, r.action, events.Evidence{RuleID: r.id, Key: "address", Value: a}
+		// several lists may carry the address; report the one with the most severe action
+		for _, l := range r.lists.Match(e.Chain, a, r.names) {
+			act := r.action
+			if l.Action != "" {
+				act = l.Action
+			}
+			if !hit || decision.Max(dec, act) != dec {
+				ev = events.Evidence{RuleID: r.id, Key: "address", Value: a, List: l.Name, ListVersion: l.Version}
+			}
+			hit, dec = true, decision.Max(dec, act)
 		}
 	}
-	return false, decision.Allow, events.Evidence{}
+	return hit, dec, ev
 }
 
 func (r *ofacRule) EvalStreaming(_ time.Time, e *events.TxEvent, _ state.View) (bool, string, events.Evidence) {
@@ -183,6 +211,486 @@ func (r *structuringRule) EvalStreaming(_ time.Time, e *events.TxEvent, st state
 	return false, decision.Allow, events.Evidence{}
 }
 
+// ------------------------ Pass-Through (Layering) Rule ------------------------
+
+type passThroughRule struct {
+	id         string
+	action     string
+	window     time.Duration
+	ratio      decimal.Decimal
+	minInbound decimal.Decimal
+}
+
+// PassThroughEvidence is the evidence value for a pass_through hit, linking the
+// inbound and outbound events that make up the flow.
+type PassThroughEvidence struct {
+	Ratio          string   `json:"ratio"`
+	InboundUSD     string   `json:"inbound_usd"`
+	OutboundUSD    string   `json:"outbound_usd"`
+	InboundEvents  []string `json:"inbound_event_ids"`
+	OutboundEvents []string `json:"outbound_event_ids"`
+}
+
+func newPassThroughRule(rd policy.RuleDef, params map[string]any) Rule {
+	window := 60 * time.Minute
+	if v, ok := params["pass_through_window_minutes"]; ok {
+		window = time.Duration(toInt(v)) * time.Minute
+	}
+	ratio := decimal.NewFromFloat(0.9)
+	if v, ok := params["pass_through_ratio"]; ok {
+		ratio = toDec(v)
+	}
+	minIn := toDec(params["pass_through_min_inbound_usd"])
+	return &passThroughRule{id: rd.ID, action: rd.Action, window: window, ratio: ratio, minInbound: minIn}
+}
+
+func (r *passThroughRule) ID() string { return r.id }
+func (r *passThroughRule) EvalInline(e *events.TxEvent) (bool, string, events.Evidence) {
+	return false, decision.Allow, events.Evidence{}
+}
+func (r *passThroughRule) EvalStreaming(now time.Time, e *events.TxEvent, st state.View) (bool, string, events.Evidence) {
+	// Only an outbound leg can complete a pass-through.
+	if e.Direction != "outbound" {
+		return false, decision.Allow, events.Evidence{}
+	}
+	var ev PassThroughEvidence
+	in, out := decimal.Zero, decimal.Zero
+	for _, f := range st.Flows(e.Subject.UserID, now, r.window) {
+		switch f.Direction {
+		case "inbound":
+			in = in.Add(f.USD)
+			ev.InboundEvents = append(ev.InboundEvents, f.EventID)
+		case "outbound":
+			// outbound value only counts once there is inbound value to pass through
+			if len(ev.InboundEvents) == 0 {
+				continue
+			}
+			out = out.Add(f.USD)
+			ev.OutboundEvents = append(ev.OutboundEvents, f.EventID)
+		}
+	}
+	if !in.IsPositive() || in.LessThan(r.minInbound) {
+		return false, decision.Allow, events.Evidence{}
+	}
+	ratio := out.Div(in)
+	if ratio.GreaterThanOrEqual(r.ratio) {
+		ev.Ratio = ratio.StringFixed(4)
+		ev.InboundUSD = in.String()
+		ev.OutboundUSD = out.String()
+		return true, r.action, events.Evidence{RuleID: r.id, Key: "pass_through_ratio", Value: ev, Limit: r.ratio.String()}
+	}
+	return false, decision.Allow, events.Evidence{}
+}
+
+// ------------------------ Counterparty Dispersion (Fan-in/Fan-out) Rule ------------------------
+
+type dispersionRule struct {
+	id        string
+	action    string
+	direction string // inbound (fan-in), outbound (fan-out), empty = both
+	window    time.Duration
+	maxCnt    int64
+	minUSD    decimal.Decimal // smaller transfers (dust) are not counted
+	sample    int
+}
+
+// DispersionEvidence is the evidence value for a counterparty_dispersion hit.
+type DispersionEvidence struct {
+	Direction string   `json:"direction,omitempty"`
+	Distinct  int64    `json:"distinct"`
+	Sample    []string `json:"sample"`
+}
+
+func newDispersionRule(rd policy.RuleDef, params map[string]any) Rule {
+	window := 24 * time.Hour
+	if v, ok := params["dispersion_window_minutes"]; ok {
+		window = time.Duration(toInt(v)) * time.Minute
+	}
+	maxCnt := int64(10)
+	if v, ok := params["dispersion_max_counterparties"]; ok {
+		maxCnt = toInt(v)
+	}
+	sample := 5
+	if v, ok := params["dispersion_sample_size"]; ok {
+		sample = int(toInt(v))
+	}
+	minUSD := toDec(params["dispersion_min_usd"])
+	return &dispersionRule{id: rd.ID, action: rd.Action, direction: rd.Direction, window: window, maxCnt: maxCnt, minUSD: minUSD, sample: sample}
+}
+
+func (r *dispersionRule) ID() string { return r.id }
+func (r *dispersionRule) EvalInline(e *events.TxEvent) (bool, string, events.Evidence) {
+	return false, decision.Allow, events.Evidence{}
+}
+func (r *dispersionRule) EvalStreaming(now time.Time, e *events.TxEvent, st state.View) (bool, string, events.Evidence) {
+	if r.direction != "" && e.Direction != r.direction {
+		return false, decision.Allow, events.Evidence{}
+	}
+	cnt, sample := st.DistinctCounterparties(e.Subject.UserID, now, r.window, r.direction, r.minUSD, r.sample)
+	if cnt > r.maxCnt {
+		ev := DispersionEvidence{Direction: r.direction, Distinct: cnt, Sample: sample}
+		return true, r.action, events.Evidence{RuleID: r.id, Key: "distinct_counterparties", Value: ev, Limit: r.maxCnt}
+	}
+	return false, decision.Allow, events.Evidence{}
+}
+
+// ------------------------ New Destination Address Rule ------------------------
+
+type newDestRule struct {
+	id          string
+	action      string
+	cooldown    time.Duration   // 0 disables the age check
+	firstUseMax decimal.Decimal // 0 disables the first-use amount check
+}
+
+// NewDestEvidence is the evidence value for a new_destination_address hit.
+type NewDestEvidence struct {
+	Address   string    `json:"address"`
+	FirstSeen time.Time `json:"first_seen"`
+	AgeHours  string    `json:"age_hours"`
+	FirstUse  bool      `json:"first_use"`
+	USDValue  string    `json:"usd_value"`
+}
+
+func newNewDestRule(rd policy.RuleDef, params map[string]any) Rule {
+	cooldown := time.Duration(toInt(params["new_address_cooldown_hours"])) * time.Hour
+	firstUseMax := toDec(params["new_address_first_use_max_usd"])
+	return &newDestRule{id: rd.ID, action: rd.Action, cooldown: cooldown, firstUseMax: firstUseMax}
+}
+
+func (r *newDestRule) ID() string { return r.id }
+
+// EvalInline knows nothing about the destination without state; the gateway
+// evaluates the rule against its own record of checked destinations.
+func (r *newDestRule) EvalInline(e *events.TxEvent) (bool, string, events.Evidence) {
+	return false, decision.Allow, events.Evidence{}
+}
+func (r *newDestRule) evalInlineState(now time.Time, e *events.TxEvent, st state.View) (bool, string, events.Evidence) {
+	return r.EvalStreaming(now, e, st)
+}
+func (r *newDestRule) EvalStreaming(now time.Time, e *events.TxEvent, st state.View) (bool, string, events.Evidence) {
+	if e.Direction != "outbound" || e.Counterparty == "" {
+		return false, decision.Allow, events.Evidence{}
+	}
+	// never seen before (state not yet updated) counts as first use right now
+	use, ok := st.Destination(e.Subject.UserID, e.Chain, e.Counterparty)
+	if !ok {
+		use = state.AddrUse{FirstSeen: now, FirstEventID: e.EventID, Uses: 1}
+	}
+	age := now.Sub(use.FirstSeen)
+	if age < 0 {
+		age = 0
+	}
+	usd := e.USDDecimal()
+	ev := NewDestEvidence{
+		Address:   e.Counterparty,
+		FirstSeen: use.FirstSeen,
+		AgeHours:  decimal.NewFromFloat(age.Hours()).StringFixed(2),
+		FirstUse:  use.FirstEventID == e.EventID,
+		USDValue:  usd.String(),
+	}
+	if r.cooldown > 0 && age < r.cooldown {
+		return true, r.action, events.Evidence{RuleID: r.id, Key: "dest_address_age", Value: ev, Limit: r.cooldown.String()}
+	}
+	if ev.FirstUse && r.firstUseMax.IsPositive() && usd.GreaterThan(r.firstUseMax) {
+		return true, r.action, events.Evidence{RuleID: r.id, Key: "dest_address_first_use_usd", Value: ev, Limit: r.firstUseMax.String()}
+	}
+	return false, decision.Allow, events.Evidence{}
+}
+
+// ------------------------ Threshold Proximity / Round Amount Rule ------------------------
+
+type thresholdProximityRule struct {
+	id         string
+	action     string
+	pct        decimal.Decimal   // band below each threshold, in percent
+	thresholds []decimal.Decimal // ascending
+	roundMult  decimal.Decimal   // 0 disables the round-amount heuristic
+	roundMin   decimal.Decimal
+}
+
+// proximityDefaultParams are the limits threshold_proximity watches when the
+// policy lists no thresholds: those a customer would structure below. Rule
+// tuning knobs (minimum inbound, first-use amount, ...) are not thresholds.
+var proximityDefaultParams = []string{"kyc_tier_caps_usd", "daily_volume_limit_usd", "structuring_small_usd"}
+
+func newThresholdProximityRule(rd policy.RuleDef, params map[string]any) Rule {
+	pct := decimal.NewFromInt(5)
+	if v, ok := params["threshold_proximity_pct"]; ok {
+		pct = toDec(v)
+	}
+	var ths []decimal.Decimal
+	if v, ok := params["threshold_proximity_thresholds_usd"]; ok {
+		ths = collectDecs(v, ths)
+	} else {
+		for _, k := range proximityDefaultParams {
+			if v, ok := params[k]; ok {
+				ths = collectDecs(v, ths)
+			}
+		}
+	}
+	sort.Slice(ths, func(i, j int) bool { return ths[i].LessThan(ths[j]) })
+	return &thresholdProximityRule{
+		id:         rd.ID,
+		action:     rd.Action,
+		pct:        pct,
+		thresholds: ths,
+		roundMult:  toDec(params["round_amount_multiple_usd"]),
+		roundMin:   toDec(params["round_amount_min_usd"]),
+	}
+}
+
+func (r *thresholdProximityRule) ID() string { return r.id }
+func (r *thresholdProximityRule) EvalInline(e *events.TxEvent) (bool, string, events.Evidence) {
+	usd := e.USDDecimal()
+	if !usd.IsPositive() {
+		return false, decision.Allow, events.Evidence{}
+	}
+	band := decimal.NewFromInt(1).Sub(r.pct.Div(decimal.NewFromInt(100)))
+	for _, t := range r.thresholds {
+		// just below: t*(1-pct) <= usd < t
+		if usd.LessThan(t) && usd.GreaterThanOrEqual(t.Mul(band)) {
+			return true, r.action, events.Evidence{RuleID: r.id, Key: "threshold_proximity", Value: usd.String(), Limit: t.String()}
+		}
+	}
+	if r.roundMult.IsPositive() && usd.GreaterThanOrEqual(r.roundMin) && usd.Mod(r.roundMult).IsZero() {
+		return true, r.action, events.Evidence{RuleID: r.id, Key: "round_amount", Value: usd.String(), Limit: r.roundMult.String()}
+	}
+	return false, decision.Allow, events.Evidence{}
+}
+func (r *thresholdProximityRule) EvalStreaming(_ time.Time, e *events.TxEvent, _ state.View) (bool, string, events.Evidence) {
+	return r.EvalInline(e)
+}
+
+// ------------------------ Sanctions Exposure (Taint) Rule ------------------------
+
+type exposureRule struct {
+	id      string
+	action  string
+	lists   *sanctions.Set
+	names   []string // lists treated as sanctioned sources, empty = all
+	maxHops int
+	fanout  int     // in-edges traced per address, largest first
+	decay   float64 // taint multiplier per intermediary hop
+	thresh  float64 // percent of incoming value
+}
+
+// ExposureEvidence is the evidence value for a sanctions_exposure hit. Path runs
+// from the sanctioned source to the subject address along the largest contributor.
+type ExposureEvidence struct {
+	ExposurePct string   `json:"exposure_pct"`
+	Hops        int      `json:"hops"`
+	Path        []string `json:"path"`
+}
+
+func newExposureRule(rd policy.RuleDef, lists *sanctions.Set, params map[string]any) Rule {
+	hops := 2
+	if v, ok := params["exposure_max_hops"]; ok {
+		hops = int(toInt(v))
+	}
+	decay := 0.5
+	if v, ok := params["exposure_decay"]; ok {
+		decay = toDec(v).InexactFloat64()
+	}
+	thresh := 10.0
+	if v, ok := params["exposure_threshold_pct"]; ok {
+		thresh = toDec(v).InexactFloat64()
+	}
+	fanout := 50
+	if v, ok := params["exposure_max_fanout"]; ok {
+		fanout = int(toInt(v))
+	}
+	return &exposureRule{id: rd.ID, action: rd.Action, lists: lists, names: rd.Lists, maxHops: hops, fanout: fanout, decay: decay, thresh: thresh}
+}
+
+func (r *exposureRule) ID() string { return r.id }
+func (r *exposureRule) EvalInline(e *events.TxEvent) (bool, string, events.Evidence) {
+	return false, decision.Allow, events.Evidence{}
+}
+func (r *exposureRule) EvalStreaming(now time.Time, e *events.TxEvent, st state.View) (bool, string, events.Evidence) {
+	return r.evalStreamingCtx(context.Background(), now, e, st)
+}
+func (r *exposureRule) evalStreamingCtx(ctx context.Context, _ time.Time, e *events.TxEvent, st state.View) (bool, string, events.Evidence) {
+	if e.Direction != "inbound" || r.maxHops < 1 {
+		return false, decision.Allow, events.Evidence{}
+	}
+	var best []string
+	var bestContrib, total, tainted float64
+	w := &walk{seen: make(map[string]bool), memo: make(map[walkKey]walkResult)}
+	for _, a := range e.Subject.Addresses {
+		k := addrKey(e.Chain, a)
+		w.seen[k] = true
+		edges, sum := r.heaviest(st.InEdges(e.Chain, a))
+		total += sum
+		for _, in := range edges {
+			usd := in.USD.InexactFloat64()
+			t, path := r.taint(ctx, st, in.Chain, in.From, 1, w)
+			tainted += usd * t
+			if c := usd * t; c > bestContrib {
+				bestContrib, best = c, append(slices.Clip(path), a)
+			}
+		}
+		delete(w.seen, k)
+	}
+	if total <= 0 || ctx.Err() != nil {
+		return false, decision.Allow, events.Evidence{}
+	}
+	pct := tainted / total * 100
+	if pct > 0 && pct >= r.thresh {
+		ev := ExposureEvidence{ExposurePct: decimal.NewFromFloat(pct).StringFixed(2), Hops: len(best) - 1, Path: best}
+		return true, r.action, events.Evidence{RuleID: r.id, Key: "sanctions_exposure", Value: ev, Limit: r.thresh}
+	}
+	return false, decision.Allow, events.Evidence{}
+}
+
+// walk is the state of one exposure evaluation: the addresses on the current
+// path, to cut cycles, and the taint of addresses already traced, by the hop
+// they were reached at. A memoized result may have been computed with a
+// different path cut, which only drops cyclic contributions.
+type walk struct {
+	seen map[string]bool
+	memo map[walkKey]walkResult
+}
+
+type walkKey struct {
+	addr string
+	hop  int
+}
+
+type walkResult struct {
+	share float64
+	path  []string
+}
+
+// taint returns the share (0..1) of value held by addr that is traceable to a
+// sanctioned address, with hop the distance from the subject (direct = 1), and
+// the strongest contributing path ending at addr. Each address is traced once
+// per hop, so the walk is linear in the edges within maxHops rather than in
+// the paths. It gives up once ctx ends.
+func (r *exposureRule) taint(ctx context.Context, st state.View, chain, addr string, hop int, w *walk) (float64, []string) {
+	k := addrKey(chain, addr)
+	if len(r.lists.Match(chain, addr, r.names)) > 0 {
+		return 1, []string{addr}
+	}
+	if hop >= r.maxHops || w.seen[k] || ctx.Err() != nil {
+		return 0, nil
+	}
+	if res, ok := w.memo[walkKey{k, hop}]; ok {
+		return res.share, res.path
+	}
+	w.seen[k] = true
+	defer delete(w.seen, k)
+
+	var best []string
+	var bestContrib, tainted float64
+	edges, total := r.heaviest(st.InEdges(chain, addr))
+	for _, in := range edges {
+		usd := in.USD.InexactFloat64()
+		t, path := r.taint(ctx, st, in.Chain, in.From, hop+1, w)
+		tainted += usd * t
+		if c := usd * t; c > bestContrib {
+			bestContrib, best = c, path
+		}
+	}
+	var res walkResult
+	if total > 0 && best != nil {
+		// copy: memoized paths are shared by every walk through addr
+		res = walkResult{share: r.decay * tainted / total, path: append(slices.Clip(best), addr)}
+	}
+	if ctx.Err() == nil {
+		w.memo[walkKey{k, hop}] = res
+	}
+	return res.share, res.path
+}
+
+// addrKey identifies addr on chain in the walk, as the state keys it.
+func addrKey(chain, addr string) string {
+	k, _ := address.Normalize(chain, addr)
+	return k.String()
+}
+
+// heaviest returns the fanout largest edges by value, and the value of all of
+// them; untraced edges count as clean.
+func (r *exposureRule) heaviest(edges []state.Edge) ([]state.Edge, float64) {
+	var total float64
+	for _, e := range edges {
+		total += e.USD.InexactFloat64()
+	}
+	if r.fanout > 0 && len(edges) > r.fanout {
+		slices.SortFunc(edges, func(a, b state.Edge) int { return b.USD.Cmp(a.USD) })
+		edges = edges[:r.fanout]
+	}
+	return edges, total
+}
+
+// ------------------------ Name Screening Rule ------------------------
+
+type nameScreeningRule struct {
+	id       string
+	action   string
+	lists    *sanctions.Set
+	names    []string // lists whose names are screened, empty = all
+	thresh   float64  // score at or above => rule action
+	reviewAt float64  // score at or above (and below thresh) => REVIEW; 0 disables
+}
+
+// NameMatchEvidence is the evidence value for a name_screening hit.
+type NameMatchEvidence struct {
+	Name        string   `json:"name"`
+	MatchedName string   `json:"matched_name"`
+	MatchedUID  string   `json:"matched_uid"`
+	MatchedDOBs []string `json:"matched_dobs,omitempty"`
+	Score       string   `json:"score"`
+}
+
+func newNameScreeningRule(rd policy.RuleDef, lists *sanctions.Set, params map[string]any) Rule {
+	thresh := 0.92
+	if v, ok := params["name_screening_threshold"]; ok {
+		thresh = toDec(v).InexactFloat64()
+	}
+	review := toDec(params["name_screening_review_threshold"]).InexactFloat64()
+	return &nameScreeningRule{id: rd.ID, action: rd.Action, lists: lists, names: rd.Lists, thresh: thresh, reviewAt: review}
+}
+
+func (r *nameScreeningRule) ID() string { return r.id }
+func (r *nameScreeningRule) EvalInline(e *events.TxEvent) (bool, string, events.Evidence) {
+	if e.Subject.Name == "" {
+		return false, decision.Allow, events.Evidence{}
+	}
+	floor := r.thresh
+	if r.reviewAt > 0 && r.reviewAt < floor {
+		floor = r.reviewAt
+	}
+	var best sanctions.NameMatch
+	var bestList *sanctions.List
+	for _, l := range r.lists.Lists() {
+		if l.Names == nil || (len(r.names) > 0 && !slices.Contains(r.names, l.Name)) {
+			continue
+		}
+		if m, ok := l.Names.Match(e.Subject.Name, e.Subject.DOB, floor); ok && m.Score > best.Score {
+			best, bestList = m, l
+		}
+	}
+	if bestList == nil {
+		return false, decision.Allow, events.Evidence{}
+	}
+	dec, limit := r.action, r.thresh
+	if best.Score < r.thresh {
+		dec, limit = decision.Review, r.reviewAt
+	}
+	ev := NameMatchEvidence{
+		Name:        e.Subject.Name,
+		MatchedName: best.Entry.Name,
+		MatchedUID:  best.Entry.UID,
+		MatchedDOBs: best.Entry.DOBs,
+		Score:       decimal.NewFromFloat(best.Score).StringFixed(3),
+	}
+	return true, dec, events.Evidence{RuleID: r.id, Key: "name_match", Value: ev, Limit: limit, List: bestList.Name, ListVersion: bestList.Names.Version}
+}
+func (r *nameScreeningRule) EvalStreaming(_ time.Time, e *events.TxEvent, _ state.View) (bool, string, events.Evidence) {
+	return r.EvalInline(e)
+}
+
 // ------------------------ helpers ------------------------
 
 func toDec(v any) decimal.Decimal {
@@ -203,6 +711,25 @@ func toDec(v any) decimal.Decimal {
 	}
 }
 
+// collectDecs appends the positive numeric values found in v (a number, list or map of numbers).
+func collectDecs(v any, out []decimal.Decimal) []decimal.Decimal {
+	switch t := v.(type) {
+	case []any:
+		for _, x := range t {
+			out = collectDecs(x, out)
+		}
+	case map[string]any:
+		for _, x := range t {
+			out = collectDecs(x, out)
+		}
+	default:
+		if d := toDec(t); d.IsPositive() {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
 func toInt(v any) int64 {
 	switch t := v.(type) {
 	case int:
@@ -0,0 +1,263 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/decision"
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+	"github.com/christophercampbell/riskr/pkg/state"
+	"github.com/shopspring/decimal"
+)
+
+func testAddr(layer, i int) string { return fmt.Sprintf("0x%020x%020x", layer+1, i+1) }
+
+// A dense layered graph has width^hops paths from the subject to the source;
+// the walk must visit each address once per hop instead.
+func TestExposureWalkIsLinearInEdges(t *testing.T) {
+	const width, layers = 40, 6
+	src := testAddr(layers, 0)
+	path := filepath.Join(t.TempDir(), "sdn.txt")
+	if err := os.WriteFile(path, []byte(src+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := sanctions.LoadFile("SDN", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// subject <- layer 0 <- layer 1 <- ... <- layer layers-1 <- src, every
+	// address in a layer funded by every address in the next
+	st := state.NewMem()
+	subject, now, n := "0xsubject", time.Now(), 0
+	flow := func(to, from string) {
+		n++
+		st.AddFlow("u", state.Flow{EventID: fmt.Sprint(n), At: now, Direction: "inbound", USD: decimal.NewFromInt(100), Address: to, Counterparty: from})
+	}
+	for i := range width {
+		flow(subject, testAddr(0, i))
+		for l := 0; l < layers-1; l++ {
+			for j := range width {
+				flow(testAddr(l, i), testAddr(l+1, j))
+			}
+		}
+		flow(testAddr(layers-1, i), src)
+	}
+
+	rd := policy.RuleDef{ID: "exp", Type: "sanctions_exposure", Action: decision.HoldAuto}
+	r := newExposureRule(rd, sanctions.NewSet(l), map[string]any{"exposure_max_hops": layers + 1, "exposure_decay": 1, "exposure_threshold_pct": 1})
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hit, _, ev := r.(ctxRule).evalStreamingCtx(ctx, now, &events.TxEvent{Direction: "inbound", Subject: events.Subject{Addresses: []string{subject}}}, st)
+	if ctx.Err() != nil {
+		t.Fatalf("walk did not finish in %v", time.Since(start))
+	}
+	if !hit {
+		t.Fatal("exposure not detected")
+	}
+	if got := ev.Value.(ExposureEvidence); got.Hops != layers+1 || got.Path[0] != src || got.Path[len(got.Path)-1] != subject {
+		t.Fatalf("evidence = %+v", got)
+	}
+}
+
+func TestThresholdProximityBand(t *testing.T) {
+	rd := policy.RuleDef{ID: "prox", Type: "threshold_proximity", Action: decision.Review}
+	r := newThresholdProximityRule(rd, map[string]any{
+		"threshold_proximity_pct":            2,
+		"threshold_proximity_thresholds_usd": []any{10000, 3000},
+		"round_amount_multiple_usd":          1000,
+		"round_amount_min_usd":               5000,
+	})
+	for _, tc := range []struct {
+		usd        string
+		key, limit string // empty key: no hit
+	}{
+		{"2939.99", "", ""},
+		{"2940", "threshold_proximity", "3000"},
+		{"2999.99", "threshold_proximity", "3000"},
+		{"3000", "", ""}, // at the threshold, not below it; under the round minimum
+		{"9799.99", "", ""},
+		{"9800", "threshold_proximity", "10000"},
+		{"9999.99", "threshold_proximity", "10000"},
+		{"10000", "round_amount", "1000"},
+		{"4000", "", ""}, // round but under the minimum
+		{"5000", "round_amount", "1000"},
+		{"5500", "", ""},
+		{"0", "", ""},
+	} {
+		hit, _, ev := r.EvalInline(&events.TxEvent{USDValue: tc.usd})
+		if hit != (tc.key != "") || hit && (ev.Key != tc.key || ev.Limit != tc.limit) {
+			t.Errorf("usd %s: hit=%v %s limit %v, want %q limit %q", tc.usd, hit, ev.Key, ev.Limit, tc.key, tc.limit)
+		}
+	}
+}
+
+// Without an explicit list only the reporting limits are thresholds, not
+// other amount params such as the pass-through minimum.
+func TestThresholdProximityDefaultThresholds(t *testing.T) {
+	rd := policy.RuleDef{ID: "prox", Type: "threshold_proximity", Action: decision.Review}
+	r := newThresholdProximityRule(rd, map[string]any{
+		"kyc_tier_caps_usd":             map[string]any{"L0": 1000},
+		"daily_volume_limit_usd":        50000,
+		"structuring_small_usd":         10000,
+		"pass_through_min_inbound_usd":  2000,
+		"new_address_first_use_max_usd": 7000,
+	})
+	for usd, want := range map[string]bool{"990": true, "49000": true, "9600": true, "1990": false, "6900": false} {
+		if hit, _, _ := r.EvalInline(&events.TxEvent{USDValue: usd}); hit != want {
+			t.Errorf("usd %s: hit=%v, want %v", usd, hit, want)
+		}
+	}
+}
+
+func TestCompileRejectsProximityPct(t *testing.T) {
+	l, err := sanctions.LoadFile("L", writeList(t, t.TempDir(), "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pct := range []any{0, -1, 100, 150, "x", 2, 99.5} {
+		p := &policy.Policy{
+			Version: "p",
+			Rules:   []policy.RuleDef{{ID: "prox", Type: "threshold_proximity", Action: decision.Review}},
+			Params:  map[string]any{"threshold_proximity_pct": pct},
+		}
+		_, err := Compile(p, sanctions.NewSet(l))
+		if ok := pct == 2 || pct == 99.5; (err == nil) != ok {
+			t.Errorf("pct %v: err = %v", pct, err)
+		}
+	}
+}
+
+// The gateway evaluates new destinations inline against its own record.
+func TestNewDestInline(t *testing.T) {
+	defs := []policy.RuleDef{{ID: "dest", Type: "new_destination_address", Action: decision.HoldAuto}}
+	snap := testSnapshot([]Rule{newNewDestRule(defs[0], map[string]any{"new_address_cooldown_hours": 24, "new_address_first_use_max_usd": 1000})}, defs...)
+	st, now := state.NewMem(), time.Now()
+	tx := func(id, dest, usd string) *events.TxEvent {
+		return &events.TxEvent{EventID: id, Chain: events.ChainInline, Direction: "outbound", Counterparty: dest, USDValue: usd, Subject: events.Subject{UserID: "u"}}
+	}
+	old, fresh := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"
+	st.AddFlow("u", state.Flow{EventID: "e0", At: now.Add(-48 * time.Hour), Direction: "outbound", Chain: events.ChainInline, Counterparty: old})
+
+	for _, tc := range []struct {
+		name string
+		st   state.View
+		e    *events.TxEvent
+		want string
+	}{
+		{"never seen", st, tx("e1", fresh, "10"), decision.HoldAuto},
+		{"past cooldown", st, tx("e2", "0x5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", "5000"), decision.Allow},
+		{"no state", nil, tx("e3", fresh, "10"), decision.Allow},
+		{"no destination", st, tx("e4", "", "10"), decision.Allow},
+	} {
+		if out := snap.EvalInline(context.Background(), tc.e, tc.st); out.Decision != tc.want {
+			t.Errorf("%s: decision = %s, want %s (%+v)", tc.name, out.Decision, tc.want, out.Evidence)
+		}
+	}
+
+	// first-use amount, with the age check off
+	snap = testSnapshot([]Rule{newNewDestRule(defs[0], map[string]any{"new_address_first_use_max_usd": 1000})}, defs...)
+	out := snap.EvalInline(context.Background(), tx("e5", fresh, "1000.01"), st)
+	if out.Decision != decision.HoldAuto || out.Evidence[0].Key != "dest_address_first_use_usd" {
+		t.Fatalf("first use over max: %s %+v", out.Decision, out.Evidence)
+	}
+	st.AddFlow("u", state.Flow{EventID: "e5", At: now, Direction: "outbound", Chain: events.ChainInline, Counterparty: fresh})
+	if out := snap.EvalInline(context.Background(), tx("e6", fresh, "5000"), st); out.Decision != decision.Allow {
+		t.Fatalf("second use: %s %+v", out.Decision, out.Evidence)
+	}
+}
+
+type testFlow struct {
+	dir string
+	usd int64
+	ago time.Duration
+}
+
+// flowState records flows for user u, the last of which is the event under
+// evaluation (the streamer applies a tx before evaluating it).
+func flowState(now time.Time, flows []testFlow, cp func(i int) string) (state.View, *events.TxEvent) {
+	st := state.NewMem()
+	var e *events.TxEvent
+	for i, f := range flows {
+		id := fmt.Sprint("e", i)
+		st.AddFlow("u", state.Flow{EventID: id, At: now.Add(-f.ago), Direction: f.dir, USD: decimal.NewFromInt(f.usd), Counterparty: cp(i)})
+		e = &events.TxEvent{EventID: id, Direction: f.dir, Counterparty: cp(i), USDValue: fmt.Sprint(f.usd), Subject: events.Subject{UserID: "u"}}
+	}
+	return st, e
+}
+
+func TestPassThrough(t *testing.T) {
+	rd := policy.RuleDef{ID: "pt", Type: "pass_through", Action: decision.Review}
+	params := map[string]any{"pass_through_window_minutes": 60, "pass_through_ratio": 0.9, "pass_through_min_inbound_usd": 1000}
+	r := newPassThroughRule(rd, params)
+	now := time.Now()
+	for _, tc := range []struct {
+		name  string
+		flows []testFlow
+		hit   bool
+	}{
+		{"inside window at ratio", []testFlow{{"inbound", 1000, 30 * time.Minute}, {"outbound", 900, 0}}, true},
+		{"below ratio", []testFlow{{"inbound", 1000, 30 * time.Minute}, {"outbound", 899, 0}}, false},
+		{"outbound legs add up", []testFlow{{"inbound", 2000, 50 * time.Minute}, {"outbound", 1000, 20 * time.Minute}, {"outbound", 800, 0}}, true},
+		{"inbound outside window", []testFlow{{"inbound", 1000, 61 * time.Minute}, {"outbound", 1000, 0}}, false},
+		{"outbound before inbound not counted", []testFlow{{"outbound", 900, 50 * time.Minute}, {"inbound", 1000, 40 * time.Minute}, {"outbound", 100, 0}}, false},
+		{"under min inbound", []testFlow{{"inbound", 999, 30 * time.Minute}, {"outbound", 999, 0}}, false},
+		{"at min inbound", []testFlow{{"inbound", 600, 30 * time.Minute}, {"inbound", 400, 20 * time.Minute}, {"outbound", 1000, 0}}, true},
+		{"inbound event", []testFlow{{"outbound", 1000, 30 * time.Minute}, {"inbound", 1000, 0}}, false},
+	} {
+		st, e := flowState(now, tc.flows, func(int) string { return "" })
+		hit, _, ev := r.EvalStreaming(now, e, st)
+		if hit != tc.hit {
+			t.Errorf("%s: hit = %v, want %v (%+v)", tc.name, hit, tc.hit, ev)
+			continue
+		}
+		if hit {
+			pt := ev.Value.(PassThroughEvidence)
+			if len(pt.InboundEvents) == 0 || len(pt.OutboundEvents) == 0 || pt.OutboundEvents[len(pt.OutboundEvents)-1] != e.EventID {
+				t.Errorf("%s: evidence = %+v", tc.name, pt)
+			}
+		}
+	}
+}
+
+func TestDispersion(t *testing.T) {
+	rd := policy.RuleDef{ID: "fanin", Type: "counterparty_dispersion", Action: decision.Review, Direction: "inbound"}
+	params := map[string]any{"dispersion_window_minutes": 60, "dispersion_max_counterparties": 3, "dispersion_min_usd": 10, "dispersion_sample_size": 2}
+	r := newDispersionRule(rd, params)
+	now := time.Now()
+	in := func(usd int64, ago time.Duration) testFlow { return testFlow{"inbound", usd, ago} }
+	distinct := func(i int) string { return testAddr(0, i) }
+	for _, tc := range []struct {
+		name  string
+		flows []testFlow
+		cp    func(int) string
+		hit   bool
+	}{
+		{"at threshold", []testFlow{in(100, 3*time.Minute), in(100, 2*time.Minute), in(100, 0)}, distinct, false},
+		{"above threshold", []testFlow{in(100, 4*time.Minute), in(100, 3*time.Minute), in(100, 2*time.Minute), in(100, 0)}, distinct, true},
+		{"repeat counterparty", []testFlow{in(100, 4*time.Minute), in(100, 3*time.Minute), in(100, 2*time.Minute), in(100, 0)}, func(i int) string { return testAddr(0, i%3) }, false},
+		{"one expired", []testFlow{in(100, 61*time.Minute), in(100, 3*time.Minute), in(100, 2*time.Minute), in(100, 0)}, distinct, false},
+		{"one under min amount", []testFlow{in(9, 4*time.Minute), in(100, 3*time.Minute), in(100, 2*time.Minute), in(100, 0)}, distinct, false},
+		{"at min amount", []testFlow{in(10, 4*time.Minute), in(100, 3*time.Minute), in(100, 2*time.Minute), in(100, 0)}, distinct, true},
+		{"other direction", []testFlow{in(100, 4*time.Minute), in(100, 3*time.Minute), in(100, 2*time.Minute), {"outbound", 100, 0}}, distinct, false},
+	} {
+		st, e := flowState(now, tc.flows, tc.cp)
+		hit, _, ev := r.EvalStreaming(now, e, st)
+		if hit != tc.hit {
+			t.Errorf("%s: hit = %v, want %v (%+v)", tc.name, hit, tc.hit, ev)
+			continue
+		}
+		if hit {
+			if d := ev.Value.(DispersionEvidence); d.Distinct != 4 || len(d.Sample) != 2 || d.Direction != "inbound" {
+				t.Errorf("%s: evidence = %+v", tc.name, d)
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package rules
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+)
+
+// Snapshot is an immutable bundle of a policy, the screening lists it was
+// compiled against and its rules. Services publish the active snapshot through
+// an atomic.Pointer and evaluate a whole event against one Load, so a decision
+// never mixes rules of one policy version with the version string of another.
+type Snapshot struct {
+	Policy  *policy.Policy
+	Version string
+	Lists   *sanctions.Set
+	Rules   []Rule
+
+	defs         map[string]policy.RuleDef // by rule ID
+	decisionTTLs map[string]time.Duration
+	ruleTTLs     map[string]time.Duration
+}
+
+// Compile builds the snapshot for p screening against lists. It fails when
+// rules reference lists or party names that lists lacks, or when rule params
+// are out of range. TTLs that do not parse as durations are ignored.
+func Compile(p *policy.Policy, lists *sanctions.Set) (*Snapshot, error) {
+	if err := checkLists(p, lists); err != nil {
+		return nil, err
+	}
+	if err := checkParams(p); err != nil {
+		return nil, err
+	}
+	s := &Snapshot{Policy: p, Version: p.Version, Lists: lists, Rules: BuildRules(p, lists, p.Params),
+		defs: make(map[string]policy.RuleDef), decisionTTLs: make(map[string]time.Duration), ruleTTLs: make(map[string]time.Duration)}
+	for dec, v := range p.TTLs {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			s.decisionTTLs[dec] = d
+		}
+	}
+	for _, rd := range p.Rules {
+		s.defs[rd.ID] = rd
+		if d, err := time.ParseDuration(rd.TTL); err == nil && d > 0 {
+			s.ruleTTLs[rd.ID] = d
+		}
+	}
+	return s, nil
+}
+
+// TTL returns how long decision dec, reached with evidence evv, holds: the
+// shortest TTL of the rules that hit, else the TTL for the decision type.
+// Zero means the decision does not expire.
+func (s *Snapshot) TTL(dec string, evv []events.Evidence) time.Duration {
+	var ttl time.Duration
+	for _, ev := range evv {
+		if d, ok := s.ruleTTLs[ev.RuleID]; ok && (ttl == 0 || d < ttl) {
+			ttl = d
+		}
+	}
+	if ttl == 0 {
+		ttl = s.decisionTTLs[dec]
+	}
+	return ttl
+}
+
+// ListVersions identifies the screening list content the snapshot screens
+// against; lists can be swapped without a new policy version, which yields a
+// new snapshot.
+func (s *Snapshot) ListVersions() string {
+	var v string
+	for _, l := range s.Lists.Lists() {
+		v += l.Name + "@" + l.Version + ","
+	}
+	return v
+}
+
+// Active holds a service's current snapshot. Evaluation Loads it once per
+// event; reloads are serialized so a policy reload racing a list swap cannot
+// store a snapshot of one policy with lists built for another.
+type Active struct {
+	mu  sync.Mutex
+	cur atomic.Pointer[Snapshot]
+}
+
+func (a *Active) Load() *Snapshot { return a.cur.Load() }
+
+// Reload compiles and stores a snapshot of p (the current policy when nil,
+// a no-op while nothing is loaded) with the lists returned by lists, then
+// passes them to commit, if set, all under the reload lock. It stores and
+// commits nothing if lists or compilation fails.
+func (a *Active) Reload(p *policy.Policy, lists func() (*sanctions.Set, error), commit func(*sanctions.Set)) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if p == nil {
+		cur := a.cur.Load()
+		if cur == nil {
+			return nil
+		}
+		p = cur.Policy
+	}
+	set, err := lists()
+	if err != nil {
+		return err
+	}
+	snap, err := Compile(p, set)
+	if err != nil {
+		return err
+	}
+	a.cur.Store(snap)
+	if commit != nil {
+		commit(set)
+	}
+	return nil
+}
+
+// Follow loads p with the active lists of reg, then recompiles the current
+// policy whenever reg swaps in a published list version, reporting failures
+// to onErr.
+func (a *Active) Follow(p *policy.Policy, reg *sanctions.Registry, onErr func(error)) error {
+	active := func() (*sanctions.Set, error) { return reg.Active(), nil }
+	if err := a.Reload(p, active, nil); err != nil {
+		return err
+	}
+	reg.OnSwap(func() {
+		if err := a.Reload(nil, active, nil); err != nil {
+			onErr(err)
+		}
+	})
+	// a version swapped in before the callback was set
+	if reg.Active() != a.Load().Lists {
+		return a.Reload(nil, active, nil)
+	}
+	return nil
+}
+
+// Apply loads p with the lists it declares, which become the active lists of
+// reg only once p has compiled against them.
+func (a *Active) Apply(p *policy.Policy, reg *sanctions.Registry) error {
+	return a.Reload(p, func() (*sanctions.Set, error) { return reg.Prepare(p.Lists) }, reg.Commit)
+}
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/christophercampbell/riskr/pkg/decision"
+	"github.com/christophercampbell/riskr/pkg/policy"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
+)
+
+func writeList(t *testing.T, dir, version string) string {
+	t.Helper()
+	path := filepath.Join(dir, version+".txt")
+	if err := os.WriteFile(path, []byte("# version: "+version+"\n0x000000000000000000000000000000000000dEaD\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestActiveReloadNilBeforeLoadIsNoop(t *testing.T) {
+	var a Active
+	called := false
+	err := a.Reload(nil, func() (*sanctions.Set, error) { called = true; return nil, nil }, nil)
+	if err != nil || called || a.Load() != nil {
+		t.Fatalf("Reload(nil) on empty Active: err=%v called=%v", err, called)
+	}
+}
+
+// A policy that does not compile must leave both the snapshot and the
+// registry's lists as they were, so later list swaps recompile the old policy.
+func TestActiveApplyRejectedKeepsLists(t *testing.T) {
+	dir := t.TempDir()
+	reg := sanctions.NewRegistry(func(s string) string { return s }, nil)
+	p0 := &policy.Policy{
+		Version: "p0",
+		Rules:   []policy.RuleDef{{ID: "R1", Type: "ofac_addr", Action: decision.RejectFatal, Lists: []string{"L"}}},
+		Lists:   []policy.ListDef{{Name: "L", Source: writeList(t, dir, "a")}},
+	}
+	if _, err := reg.Apply(p0.Lists); err != nil {
+		t.Fatal(err)
+	}
+	var a Active
+	if err := a.Follow(p0, reg, func(err error) { t.Error(err) }); err != nil {
+		t.Fatal(err)
+	}
+
+	// p1 drops L but a rule still references it
+	p1 := &policy.Policy{Version: "p1", Rules: p0.Rules, Lists: []policy.ListDef{{Name: "M", Source: writeList(t, dir, "b")}}}
+	if err := a.Apply(p1, reg); err == nil {
+		t.Fatal("Apply accepted a policy referencing a missing list")
+	}
+	if v := a.Load().Version; v != "p0" {
+		t.Fatalf("snapshot policy = %s, want p0", v)
+	}
+	if reg.Active().Get("L") == nil || reg.Active().Get("M") != nil {
+		t.Fatal("registry lists changed by the rejected policy")
+	}
+
+	l, err := sanctions.LoadFile("L", writeList(t, dir, "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := reg.Swap(l); !ok || err != nil {
+		t.Fatalf("Swap = %v, %v", ok, err)
+	}
+	if got := a.Load(); got.Version != "p0" || got.ListVersions() != "L@c," {
+		t.Fatalf("after swap: %s %s, want p0 L@c", got.Version, got.ListVersions())
+	}
+}
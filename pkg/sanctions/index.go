@@ -0,0 +1,168 @@
+package sanctions
+
+import (
+	"hash/fnv"
+	"runtime"
+	"sort"
+	"sync"
+	"weak"
+)
+
+// bloomMinEntries is the list size from which lookups go through a bloom
+// pre-filter; most screened addresses are misses, which it answers without
+// touching the sorted keys.
+const bloomMinEntries = 4096
+
+// Index is an immutable, compact set of address keys: all keys concatenated
+// in sorted order plus offsets, searched by binary search. Compared with a
+// map[string]struct{} it costs ~4 bytes of overhead per key instead of ~50.
+// An Index is built once per list content and shared by every List, Set and
+// rule that screens that content.
+type Index struct {
+	data  []byte
+	offs  []uint32 // key i is data[offs[i]:offs[i+1]]
+	bloom *bloom
+}
+
+func newIndex(keys []string) *Index {
+	sort.Strings(keys)
+	size := 0
+	for _, k := range keys {
+		size += len(k)
+	}
+	ix := &Index{data: make([]byte, 0, size), offs: make([]uint32, 0, len(keys)+1)}
+	if len(keys) >= bloomMinEntries {
+		ix.bloom = newBloom(len(keys))
+	}
+	for i, k := range keys {
+		if i > 0 && k == keys[i-1] {
+			continue
+		}
+		ix.offs = append(ix.offs, uint32(len(ix.data)))
+		ix.data = append(ix.data, k...)
+		if ix.bloom != nil {
+			ix.bloom.add(k)
+		}
+	}
+	ix.offs = append(ix.offs, uint32(len(ix.data)))
+	return ix
+}
+
+func (ix *Index) key(i int) string { return string(ix.data[ix.offs[i]:ix.offs[i+1]]) }
+
+// Len returns the number of keys.
+func (ix *Index) Len() int { return len(ix.offs) - 1 }
+
+// Has reports whether key is in the index.
+func (ix *Index) Has(key string) bool {
+	if ix.bloom != nil && !ix.bloom.mayContain(key) {
+		return false
+	}
+	// compare in place; string(b) in a comparison does not allocate
+	lo, hi := 0, ix.Len()
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if string(ix.data[ix.offs[m]:ix.offs[m+1]]) < key {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+	return lo < ix.Len() && string(ix.data[ix.offs[lo]:ix.offs[lo+1]]) == key
+}
+
+// SizeBytes approximates the index's heap footprint.
+func (ix *Index) SizeBytes() int {
+	n := cap(ix.data) + 4*cap(ix.offs)
+	if ix.bloom != nil {
+		n += 8 * len(ix.bloom.bits)
+	}
+	return n
+}
+
+// ------------------------ bloom filter ------------------------
+
+// bloom is a fixed-size bloom filter sized for ~1% false positives
+// (10 bits and 7 probes per key), using double hashing over FNV-64a.
+type bloom struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+func newBloom(n int) *bloom {
+	m := uint64(n) * 10
+	return &bloom{bits: make([]uint64, (m+63)/64), m: m, k: 7}
+}
+
+func bloomHash(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+	return h1, h1>>33 | h1<<31 | 1
+}
+
+func (b *bloom) add(key string) {
+	h1, h2 := bloomHash(key)
+	for i := uint64(0); i < b.k; i++ {
+		p := (h1 + i*h2) % b.m
+		b.bits[p/64] |= 1 << (p % 64)
+	}
+}
+
+func (b *bloom) mayContain(key string) bool {
+	h1, h2 := bloomHash(key)
+	for i := uint64(0); i < b.k; i++ {
+		p := (h1 + i*h2) % b.m
+		if b.bits[p/64]&(1<<(p%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ------------------------ shared cache ------------------------
+
+// parsed is the immutable result of parsing one list content.
+type parsed struct {
+	version string
+	invalid int
+	index   *Index
+}
+
+// cache shares parsed list content by digest. Entries are weak so an index is
+// released once no List references it; policy reloads and object store
+// re-deliveries of an unchanged list reuse the existing index.
+var cache = struct {
+	mu sync.Mutex
+	m  map[string]weak.Pointer[parsed]
+}{m: make(map[string]weak.Pointer[parsed])}
+
+func cached(digest string) *parsed {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if wp, ok := cache.m[digest]; ok {
+		return wp.Value()
+	}
+	return nil
+}
+
+func remember(digest string, p *parsed) *parsed {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if wp, ok := cache.m[digest]; ok {
+		if existing := wp.Value(); existing != nil {
+			return existing // lost a race with a concurrent load
+		}
+	}
+	wp := weak.Make(p)
+	cache.m[digest] = wp
+	runtime.AddCleanup(p, func(d string) {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		if cur, ok := cache.m[d]; ok && cur == wp {
+			delete(cache.m, d)
+		}
+	}, digest)
+	return p
+}
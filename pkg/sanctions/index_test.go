@@ -0,0 +1,117 @@
+package sanctions
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/christophercampbell/riskr/pkg/address"
+)
+
+// testKeys returns n distinct EVM address keys.
+func testKeys(tb testing.TB, n, offset int) []string {
+	tb.Helper()
+	keys := make([]string, n)
+	for i := range keys {
+		k, err := address.Normalize("ETH", fmt.Sprintf("0x%040x", uint64(i+offset)*0x9e3779b97f4a7c15))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		keys[i] = k.String()
+	}
+	return keys
+}
+
+func TestIndexHas(t *testing.T) {
+	for _, n := range []int{0, 1, 100, bloomMinEntries} {
+		keys := testKeys(t, n, 0)
+		ix := newIndex(append([]string(nil), keys...))
+		if ix.Len() != n {
+			t.Fatalf("n=%d: Len = %d", n, ix.Len())
+		}
+		for _, k := range keys {
+			if !ix.Has(k) {
+				t.Fatalf("n=%d: missing %s", n, k)
+			}
+		}
+		for _, k := range testKeys(t, 100, n) {
+			if ix.Has(k) {
+				t.Fatalf("n=%d: unexpected %s", n, k)
+			}
+		}
+	}
+}
+
+var sizes = []int{1_000, 100_000, 1_000_000}
+
+func BenchmarkIndexHas(b *testing.B) {
+	for _, n := range sizes {
+		keys := testKeys(b, n, 0)
+		ix := newIndex(append([]string(nil), keys...))
+		misses := testKeys(b, 1024, n)
+		b.Run(fmt.Sprintf("n=%d/hit", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				ix.Has(keys[i%len(keys)])
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/miss", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				ix.Has(misses[i%len(misses)])
+			}
+		})
+	}
+}
+
+// heapDelta returns the live heap retained by the result of build.
+func heapDelta(build func() any) int {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	v := build()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(v)
+	return int(after.HeapAlloc) - int(before.HeapAlloc)
+}
+
+func keyBytes(keys []string) int {
+	n := 0
+	for _, k := range keys {
+		n += len(k)
+	}
+	return n
+}
+
+// BenchmarkIndexBuild reports build allocations and the retained heap per key
+// (key bytes included), against a map[string]struct{} of the same keys.
+func BenchmarkIndexBuild(b *testing.B) {
+	for _, n := range sizes {
+		keys := testKeys(b, n, 0)
+		build := map[string]func() any{
+			"index": func() any { return newIndex(append([]string(nil), keys...)) },
+			// the map shares the caller's key strings; count them as the index does
+			"map": func() any {
+				m := make(map[string]struct{}, len(keys))
+				for _, k := range keys {
+					m[k] = struct{}{}
+				}
+				return m
+			},
+		}
+		for _, name := range []string{"index", "map"} {
+			b.Run(fmt.Sprintf("n=%d/%s", n, name), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					build[name]()
+				}
+				retained := heapDelta(build[name])
+				if name == "map" {
+					retained += keyBytes(keys)
+				}
+				b.ReportMetric(float64(retained)/float64(n), "B/key")
+			})
+		}
+	}
+}
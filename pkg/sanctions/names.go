@@ -0,0 +1,307 @@
+package sanctions
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NameEntry is one sanctioned party name (primary or alias) with its dates of birth.
+type NameEntry struct {
+	UID  string
+	Name string
+	DOBs []string // as published, e.g. "01 Jan 1980", "1975", "circa 1970"
+
+	norm   string   // normalized name
+	sorted string   // normalized tokens in sorted order
+	tokens []string // normalized tokens
+	years  []int    // birth years parsed from DOBs
+}
+
+// NameList is the set of names accompanying a screening list.
+type NameList struct {
+	Source  string
+	Version string
+	entries []NameEntry
+	// token prefix -> entries with a token starting with it, to narrow Match
+	byPrefix map[string][]int32
+}
+
+// prefixLen is the length of the token prefixes indexed. A name is only
+// scored when one of its tokens shares a prefix with one of the entry's; a
+// misspelling in the first letters of every token goes unmatched.
+const prefixLen = 2
+
+func tokenPrefix(tok string) string {
+	r := []rune(tok)
+	return string(r[:min(len(r), prefixLen)])
+}
+
+// NameMatch is the best match found for a screened name.
+type NameMatch struct {
+	Entry NameEntry
+	Score float64
+}
+
+// LoadNames reads a names file: `<uid>\t<name>\t<dob>[;<dob>...]` per line,
+// with `#` comments and an optional `# version: <v>` header.
+func LoadNames(path string) (*NameList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	nl := &NameList{Source: path, byPrefix: make(map[string][]int32)}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		ln := strings.TrimSpace(sc.Text())
+		if ln == "" {
+			continue
+		}
+		if strings.HasPrefix(ln, "#") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(strings.TrimPrefix(ln, "#")), "version:"); ok && nl.Version == "" {
+				nl.Version = strings.TrimSpace(v)
+			}
+			continue
+		}
+		parts := strings.Split(ln, "\t")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("names %s: malformed line %q", path, ln)
+		}
+		e := NameEntry{UID: parts[0], Name: parts[1]}
+		if len(parts) > 2 && parts[2] != "" {
+			e.DOBs = strings.Split(parts[2], ";")
+		}
+		nl.add(e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("names %s: %w", path, err)
+	}
+	return nl, nil
+}
+
+func (nl *NameList) add(e NameEntry) {
+	e.tokens = nameTokens(e.Name)
+	if len(e.tokens) == 0 {
+		return
+	}
+	e.norm = strings.Join(e.tokens, " ")
+	st := append([]string(nil), e.tokens...)
+	sort.Strings(st)
+	e.sorted = strings.Join(st, " ")
+	for _, d := range e.DOBs {
+		e.years = append(e.years, dobYears(d)...)
+	}
+	i := int32(len(nl.entries))
+	for _, t := range e.tokens {
+		p := tokenPrefix(t)
+		if ix := nl.byPrefix[p]; len(ix) == 0 || ix[len(ix)-1] != i {
+			nl.byPrefix[p] = append(ix, i)
+		}
+	}
+	nl.entries = append(nl.entries, e)
+}
+
+// Len returns the number of names (aliases included).
+func (nl *NameList) Len() int { return len(nl.entries) }
+
+// Match returns the best scoring entry for name at or above minScore, among
+// the entries sharing a token prefix with it. When dob
+// (any format containing a 4-digit year) is given, entries with known birth
+// years more than a year apart are skipped.
+func (nl *NameList) Match(name, dob string, minScore float64) (NameMatch, bool) {
+	toks := nameTokens(name)
+	if len(toks) == 0 {
+		return NameMatch{}, false
+	}
+	norm := strings.Join(toks, " ")
+	st := append([]string(nil), toks...)
+	sort.Strings(st)
+	sorted := strings.Join(st, " ")
+	years := dobYears(dob)
+
+	var cand []int32
+	for _, t := range toks {
+		cand = append(cand, nl.byPrefix[tokenPrefix(t)]...)
+	}
+	slices.Sort(cand)
+	var best NameMatch
+	for _, i := range slices.Compact(cand) {
+		e := nl.entries[i]
+		if len(years) > 0 && len(e.years) > 0 && !yearsOverlap(years, e.years) {
+			continue
+		}
+		// cheap length filter: JW cannot reach high scores on very different lengths
+		if l1, l2 := len(norm), len(e.norm); l1*2 < l2 || l2*2 < l1 {
+			continue
+		}
+		s := jaroWinkler(norm, e.norm)
+		if v := jaroWinkler(sorted, e.sorted); v > s {
+			s = v // token reordering: "SMITH John" vs "John Smith"
+		}
+		if v := tokenSetScore(toks, e.tokens); v > s {
+			s = v
+		}
+		if s > best.Score {
+			best = NameMatch{Entry: e, Score: s}
+		}
+	}
+	return best, best.Score >= minScore && best.Score > 0
+}
+
+// tokenSetScore averages, over the tokens of both lists, each token's best
+// Jaro-Winkler match in the other list. It tolerates a missing middle name but
+// scores it below a full match, as the unmatched token counts against it.
+func tokenSetScore(a, b []string) float64 {
+	if min(len(a), len(b)) < 2 {
+		return 0 // a single token matching part of a full name is too weak
+	}
+	return (bestTokens(a, b) + bestTokens(b, a)) / float64(len(a)+len(b))
+}
+
+// bestTokens sums each token's best Jaro-Winkler match in other.
+func bestTokens(toks, other []string) float64 {
+	sum := 0.0
+	for _, x := range toks {
+		bestTok := 0.0
+		for _, y := range other {
+			if v := jaroWinkler(x, y); v > bestTok {
+				bestTok = v
+			}
+		}
+		sum += bestTok
+	}
+	return sum
+}
+
+// ------------------------ normalization ------------------------
+
+// translit maps common non-ASCII letters to ASCII (Latin diacritics and Cyrillic).
+var translit = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i", 'ł': "l", 'ľ': "l",
+	'ñ': "n", 'ń': "n", 'ň': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o",
+	'œ': "oe", 'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ý': "y", 'ÿ': "y", 'ž': "z", 'ź': "z", 'ż': "z",
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z", 'и': "i", 'й': "y",
+	'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f",
+	'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+}
+
+// nameTokens lower-cases, transliterates and splits a name into alphanumeric
+// tokens; punctuation (commas in "LAST, First", hyphens, dots) separates tokens.
+func nameTokens(name string) []string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if t, ok := translit[r]; ok {
+			b.WriteString(t)
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r) // ASCII, or a script without transliteration: keep as-is
+			continue
+		}
+		b.WriteByte(' ')
+	}
+	return strings.Fields(b.String())
+}
+
+var yearRe = regexp.MustCompile(`\b(1[89]\d\d|20\d\d)\b`)
+
+func dobYears(dob string) []int {
+	var out []int
+	for _, m := range yearRe.FindAllString(dob, -1) {
+		y, _ := strconv.Atoi(m)
+		out = append(out, y)
+	}
+	return out
+}
+
+func yearsOverlap(a, b []int) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x-y <= 1 && y-x <= 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ------------------------ Jaro-Winkler ------------------------
+
+func jaroWinkler(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ar, br := []rune(a), []rune(b)
+	if len(ar) == 0 || len(br) == 0 {
+		return 0
+	}
+	window := max(len(ar), len(br))/2 - 1
+	if window < 0 {
+		window = 0
+	}
+	am := make([]bool, len(ar))
+	bm := make([]bool, len(br))
+	matches := 0
+	for i := range ar {
+		lo, hi := max(0, i-window), min(len(br), i+window+1)
+		for j := lo; j < hi; j++ {
+			if !bm[j] && ar[i] == br[j] {
+				am[i], bm[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+	trans, j := 0, 0
+	for i := range ar {
+		if !am[i] {
+			continue
+		}
+		for !bm[j] {
+			j++
+		}
+		if ar[i] != br[j] {
+			trans++
+		}
+		j++
+	}
+	m := float64(matches)
+	jaro := (m/float64(len(ar)) + m/float64(len(br)) + (m-float64(trans)/2)/m) / 3
+	prefix := 0
+	for prefix < min(4, len(ar), len(br)) && ar[prefix] == br[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// ------------------------ SDN names ------------------------
+
+// WriteNames writes names in names file format with a version header.
+func WriteNames(w io.Writer, version, source string, names []NameEntry) error {
+	if _, err := fmt.Fprintf(w, "# %s: <uid>\\t<name>\\t<dob>[;<dob>...] per line\n# version: %s\n# entries: %d\n",
+		source, version, len(names)); err != nil {
+		return err
+	}
+	for _, n := range names {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", n.UID, n.Name, strings.Join(n.DOBs, ";")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package sanctions
+
+import (
+	"fmt"
+	"testing"
+)
+
+func testNames(names ...string) *NameList {
+	nl := &NameList{byPrefix: make(map[string][]int32)}
+	for i, n := range names {
+		nl.add(NameEntry{UID: fmt.Sprint(i), Name: n})
+	}
+	return nl
+}
+
+func TestTokenSetScorePenalizesUnmatchedTokens(t *testing.T) {
+	full := tokenSetScore(nameTokens("Ivan Petrovich Sidorov"), nameTokens("Sidorov Ivan Petrovich"))
+	subset := tokenSetScore(nameTokens("Ivan Sidorov"), nameTokens("Ivan Petrovich Sidorov"))
+	if full != 1 {
+		t.Fatalf("reordered full name = %v, want 1", full)
+	}
+	if subset >= 0.95 || subset < 0.88 {
+		t.Fatalf("two of three tokens = %v, want review range [0.88, 0.95)", subset)
+	}
+}
+
+func TestNameListMatch(t *testing.T) {
+	nl := testNames("Ivan Petrovich SIDOROV", "ACME MIXING SERVICES LTD", "Maria Gonzalez")
+	for _, tc := range []struct {
+		name, want string
+	}{
+		{"SIDOROV, Ivan Petrovich", "0"},
+		{"Ivan Petrovitch Sidorov", "0"}, // misspelt
+		{"Acme Mixing Services Ltd.", "1"},
+		{"Mariah Gonzales", "2"},
+		{"John Smith", ""},
+	} {
+		m, ok := nl.Match(tc.name, "", 0.9)
+		if got := m.Entry.UID; !ok && tc.want != "" || ok && got != tc.want {
+			t.Errorf("Match(%q) = %q %v (%.3f), want %q", tc.name, got, ok, m.Score, tc.want)
+		}
+	}
+}
+
+// testName returns a pseudo-random name of three 4-7 letter tokens.
+func testName(i int) string {
+	x := uint64(i+1) * 0x9e3779b97f4a7c15
+	name := ""
+	for t := range 3 {
+		if t > 0 {
+			name += " "
+		}
+		for range 4 + x%4 {
+			x = x*6364136223846793005 + 1442695040888963407
+			name += string(rune('a' + x>>59%26))
+		}
+	}
+	return name
+}
+
+// BenchmarkNameListMatch screens one name against lists of n entries.
+func BenchmarkNameListMatch(b *testing.B) {
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		names := make([]string, n)
+		for i := range names {
+			names[i] = testName(i)
+		}
+		nl := testNames(names...)
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				nl.Match(names[i%n], "", 0.88)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Package sanctions loads named address screening lists (OFAC SDN, EU, UN,
+// internal blocklists, mixer/darknet tags, ...) and answers membership queries.
+package sanctions
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/christophercampbell/riskr/pkg/address"
+	"github.com/christophercampbell/riskr/pkg/policy"
+)
+
+// DefaultList names the list loaded from config `sanctions.file` when the
+// policy does not declare any screening lists.
+const DefaultList = "default"
+
+// List is a named, versioned set of screened addresses. Lists with identical
+// content share one immutable Index.
+type List struct {
+	Name    string
+	Source  string
+	Version string
+	Action  string    // decision for hits on this list; empty = rule action
+	Invalid int       // entries that failed address validation (still screened)
+	Names   *NameList // party names for name screening, if configured
+	pin     string    // version pinned by the policy, if any
+	src     *parsed
+}
+
+// Parse reads one entry per line, either `<address>` or `<chain> <address>`.
+// Blank lines and `#` comments are skipped; a `# version: <v>` header sets the
+// list version. Without one the version is a short content hash. Addresses are
+// keyed by address.Key; entries that fail validation are kept under their
+// best-effort key and also returned in invalid.
+func Parse(r io.Reader) (version string, addrs map[string]struct{}, invalid []string, err error) {
+	version, keys, invalid, err := parseKeys(r)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	addrs = make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		addrs[k] = struct{}{}
+	}
+	return version, addrs, invalid, nil
+}
+
+func parseKeys(r io.Reader) (version string, keys, invalid []string, err error) {
+	h := sha256.New()
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		ln := strings.TrimSpace(sc.Text())
+		if ln == "" {
+			continue
+		}
+		if strings.HasPrefix(ln, "#") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(strings.TrimPrefix(ln, "#")), "version:"); ok && version == "" {
+				version = strings.TrimSpace(v)
+			}
+			continue
+		}
+		fields := strings.Fields(ln)
+		chain := ""
+		if len(fields) > 1 {
+			chain = fields[0]
+		}
+		k, kerr := address.Normalize(chain, fields[len(fields)-1])
+		if kerr != nil {
+			invalid = append(invalid, ln)
+		}
+		keys = append(keys, k.String())
+		h.Write([]byte(k.String() + "\n"))
+	}
+	if err = sc.Err(); err != nil {
+		return "", nil, nil, err
+	}
+	if version == "" {
+		version = "sha256:" + hex.EncodeToString(h.Sum(nil))[:12]
+	}
+	return version, keys, invalid, nil
+}
+
+// LoadFile loads the list stored at path under the given name.
+func LoadFile(name, path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return read(name, path, f)
+}
+
+// read loads list content, reusing the shared index when the same content has
+// already been loaded (by this or any other list, set or registry).
+func read(name, source string, r io.Reader) (*List, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("sanctions list %s: %w", name, err)
+	}
+	sum := sha256.Sum256(b)
+	digest := hex.EncodeToString(sum[:])
+	p := cached(digest)
+	if p == nil {
+		ver, keys, invalid, err := parseKeys(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("sanctions list %s: %w", name, err)
+		}
+		p = remember(digest, &parsed{version: ver, invalid: len(invalid), index: newIndex(keys)})
+	}
+	return &List{Name: name, Source: source, Version: p.version, Invalid: p.invalid, src: p}, nil
+}
+
+// Contains reports whether addr on chain is on the list. chain may be empty or
+// a non-chain label, see address.Normalize.
+func (l *List) Contains(chain, addr string) bool {
+	k, _ := address.Normalize(chain, addr)
+	return l.src.index.Has(k.String())
+}
+
+// Len returns the number of addresses on the list.
+func (l *List) Len() int { return l.src.index.Len() }
+
+// Index returns the list's shared index.
+func (l *List) Index() *Index { return l.src.index }
+
+// Set is an immutable, ordered collection of lists, as declared in the policy.
+// Swapping a list version (see Registry) builds a new Set, so everything
+// screening against one Set sees the same list versions.
+type Set struct {
+	lists []*List
+}
+
+// NewSet builds a set from lists, skipping nils.
+func NewSet(lists ...*List) *Set {
+	ls := make([]*List, 0, len(lists))
+	for _, l := range lists {
+		if l != nil {
+			ls = append(ls, l)
+		}
+	}
+	return &Set{lists: ls}
+}
+
+// Build loads the lists declared in defs, resolving sources with resolve. When
+// defs is empty the fallback list (from config) is used on its own.
+func Build(defs []policy.ListDef, resolve func(string) string, fallback *List) (*Set, error) {
+	if len(defs) == 0 {
+		return NewSet(fallback), nil
+	}
+	ls := make([]*List, 0, len(defs))
+	for _, d := range defs {
+		l, err := LoadFile(d.Name, resolve(d.Source))
+		if err != nil {
+			return nil, err
+		}
+		if d.Version != "" && l.Version != d.Version {
+			return nil, fmt.Errorf("sanctions list %s: policy pins version %s, source has %s", d.Name, d.Version, l.Version)
+		}
+		l.Action = d.Action
+		l.pin = d.Version
+		if d.Names != "" {
+			if l.Names, err = LoadNames(resolve(d.Names)); err != nil {
+				return nil, fmt.Errorf("sanctions list %s: %w", d.Name, err)
+			}
+		}
+		ls = append(ls, l)
+	}
+	return NewSet(ls...), nil
+}
+
+// Lists returns the lists in declaration order.
+func (s *Set) Lists() []*List { return s.lists }
+
+// Get returns the named list or nil.
+func (s *Set) Get(name string) *List {
+	for _, l := range s.Lists() {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// with returns a new set in which the list named nl.Name is replaced by a copy
+// of nl carrying the policy settings (action, pin) of the list it replaces,
+// or nil if s has no such list.
+func (s *Set) with(nl *List) (*Set, error) {
+	next := make([]*List, len(s.lists))
+	copy(next, s.lists)
+	found := false
+	for i, l := range next {
+		if l.Name != nl.Name {
+			continue
+		}
+		if l.pin != "" && nl.Version != l.pin {
+			return nil, fmt.Errorf("sanctions list %s: policy pins version %s, got %s", l.Name, l.pin, nl.Version)
+		}
+		cp := *nl
+		cp.Action, cp.pin = l.Action, l.pin
+		if cp.Names == nil {
+			cp.Names = l.Names // names are file-based; keep them across address updates
+		}
+		next[i], found = &cp, true
+	}
+	if !found {
+		return nil, nil
+	}
+	return &Set{lists: next}, nil
+}
+
+// Match returns every list among names (all lists if names is empty) that contains addr on chain.
+func (s *Set) Match(chain, addr string, names []string) []*List {
+	var out []*List
+	for _, l := range s.Lists() {
+		if len(names) > 0 && !contains(names, l.Name) {
+			continue
+		}
+		if l.Contains(chain, addr) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func contains(names []string, n string) bool {
+	for _, x := range names {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package sanctions
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/christophercampbell/riskr/pkg/policy"
+)
+
+func TestBuildRejectsPinMismatch(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1.txt", "# version: v1\n0x000000000000000000000000000000000000dEaD\n")
+	write("hashed.txt", "0x000000000000000000000000000000000000dEaD\n")
+	resolve := func(s string) string { return filepath.Join(dir, s) }
+	hashed, err := LoadFile("h", resolve("hashed.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		source, pin string
+		ok          bool
+	}{
+		{"v1.txt", "v1", true},
+		{"v1.txt", "v2", false},
+		{"hashed.txt", hashed.Version, true},
+		{"hashed.txt", "v1", false},
+	} {
+		s, err := Build([]policy.ListDef{{Name: "L", Source: tc.source, Version: tc.pin}}, resolve, nil)
+		if (err == nil) != tc.ok {
+			t.Fatalf("%s pinned %s: err = %v", tc.source, tc.pin, err)
+		}
+		if err == nil && s.Get("L").Version != tc.pin {
+			t.Fatalf("%s pinned %s: version = %s", tc.source, tc.pin, s.Get("L").Version)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package sanctions
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/address"
+)
+
+// Formats accepted by Import.
+const (
+	FormatSDNXML = "sdn-xml"
+	FormatSDNCSV = "sdn-csv"
+)
+
+// sdnIDPrefix marks digital currency address ids, e.g. "Digital Currency Address - XBT".
+const sdnIDPrefix = "Digital Currency Address - "
+
+// Entry is one screened address extracted from a source list.
+type Entry struct {
+	Chain   string // source chain code, e.g. XBT, ETH, TRX
+	Address string
+	UID     string // source entry id
+}
+
+// Key identifies the entry within a list file ("<chain> <address>").
+func (e Entry) Key() string { return e.Chain + " " + e.Address }
+
+// Import parses an OFAC SDN export in the given format, returning the
+// digital currency addresses and the names (with aliases) of individuals and
+// entities. Version is the publish date when the source carries one,
+// otherwise the import time.
+func Import(format string, r io.Reader, now time.Time) (version string, entries []Entry, names []NameEntry, err error) {
+	switch format {
+	case FormatSDNXML:
+		version, entries, names, err = parseSDNXML(r)
+	case FormatSDNCSV:
+		entries, names, err = parseSDNCSV(r)
+	default:
+		return "", nil, nil, fmt.Errorf("unknown sanctions format %q (want %s|%s)", format, FormatSDNXML, FormatSDNCSV)
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if version == "" {
+		version = now.UTC().Format("2006-01-02T150405Z")
+	}
+	return version, normalize(entries), names, nil
+}
+
+type sdnName struct {
+	First string `xml:"firstName"`
+	Last  string `xml:"lastName"`
+}
+
+func (n sdnName) full() string { return strings.TrimSpace(n.First + " " + n.Last) }
+
+type sdnXML struct {
+	Publish struct {
+		Date string `xml:"Publish_Date"`
+	} `xml:"publshInformation"` // sic, as published by OFAC
+	Entries []struct {
+		UID  string `xml:"uid"`
+		Type string `xml:"sdnType"`
+		sdnName
+		IDs []struct {
+			Type   string `xml:"idType"`
+			Number string `xml:"idNumber"`
+		} `xml:"idList>id"`
+		AKAs []sdnName `xml:"akaList>aka"`
+		DOBs []string  `xml:"dateOfBirthList>dateOfBirthItem>dateOfBirth"`
+	} `xml:"sdnEntry"`
+}
+
+func parseSDNXML(r io.Reader) (string, []Entry, []NameEntry, error) {
+	var doc sdnXML
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return "", nil, nil, fmt.Errorf("sdn xml: %w", err)
+	}
+	var out []Entry
+	var names []NameEntry
+	for _, e := range doc.Entries {
+		for _, id := range e.IDs {
+			chain, ok := strings.CutPrefix(strings.TrimSpace(id.Type), sdnIDPrefix)
+			if !ok {
+				continue
+			}
+			out = append(out, Entry{Chain: chain, Address: id.Number, UID: e.UID})
+		}
+		// vessels and aircraft are not screened by name
+		if e.Type != "Individual" && e.Type != "Entity" {
+			continue
+		}
+		for _, n := range append([]sdnName{e.sdnName}, e.AKAs...) {
+			if full := n.full(); full != "" {
+				names = append(names, NameEntry{UID: e.UID, Name: full, DOBs: e.DOBs})
+			}
+		}
+	}
+	version := ""
+	if d, err := time.Parse("01/02/2006", strings.TrimSpace(doc.Publish.Date)); err == nil {
+		version = d.Format("2006-01-02")
+	}
+	return version, out, names, nil
+}
+
+// sdn.csv has no id columns; addresses live in the free-text remarks
+// ("... Digital Currency Address - XBT 1Abc...; alt. Digital Currency Address - ETH 0x...;").
+var (
+	sdnRemarkAddr = regexp.MustCompile(`Digital Currency Address - ([A-Za-z0-9]+)[\s:]+([A-Za-z0-9]+)`)
+	sdnRemarkDOB  = regexp.MustCompile(`DOB ([^;]+)`)
+)
+
+// parseSDNCSV reads sdn.csv. Aliases are published separately (alt.csv) and
+// are not picked up here.
+func parseSDNCSV(r io.Reader) ([]Entry, []NameEntry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	var out []Entry
+	var names []NameEntry
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("sdn csv: %w", err)
+		}
+		if len(rec) < 3 {
+			continue
+		}
+		// ent_num, SDN_Name, SDN_Type, ..., Remarks (last column)
+		uid, remarks := strings.TrimSpace(rec[0]), rec[len(rec)-1]
+		for _, m := range sdnRemarkAddr.FindAllStringSubmatch(remarks, -1) {
+			out = append(out, Entry{Chain: m[1], Address: m[2], UID: uid})
+		}
+		// SDN_Type is "individual" or "-0-" for entities; vessels/aircraft are skipped
+		if t := strings.TrimSpace(rec[2]); t == "individual" || t == "-0-" {
+			n := NameEntry{UID: uid, Name: strings.TrimSpace(rec[1])}
+			for _, m := range sdnRemarkDOB.FindAllStringSubmatch(remarks, -1) {
+				n.DOBs = append(n.DOBs, strings.TrimSpace(m[1]))
+			}
+			names = append(names, n)
+		}
+	}
+	return out, names, nil
+}
+
+// normalize upper-cases chain codes, canonicalizes addresses per chain (see
+// address.Normalize), drops empties and duplicates, and sorts by (chain,
+// address) so list files diff cleanly.
+func normalize(in []Entry) []Entry {
+	seen := make(map[string]struct{}, len(in))
+	out := make([]Entry, 0, len(in))
+	for _, e := range in {
+		e.Chain = strings.ToUpper(strings.TrimSpace(e.Chain))
+		e.Address = strings.TrimSpace(e.Address)
+		if e.Address == "" || e.Address == "-0-" {
+			continue
+		}
+		k, _ := address.Normalize(e.Chain, e.Address)
+		e.Address = k.Address
+		dk := e.Chain + " " + k.String()
+		if _, dup := seen[dk]; dup {
+			continue
+		}
+		seen[dk] = struct{}{}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Chain != out[j].Chain {
+			return out[i].Chain < out[j].Chain
+		}
+		return out[i].Address < out[j].Address
+	})
+	return out
+}
+
+// WriteList writes entries in list file format with a version header.
+func WriteList(w io.Writer, version, source string, importedAt time.Time, entries []Entry) error {
+	if _, err := fmt.Fprintf(w, "# %s: <chain> <address> per line\n# version: %s\n# imported_at: %s\n# entries: %d\n",
+		source, version, importedAt.UTC().Format(time.RFC3339), len(entries)); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if _, err := fmt.Fprintln(w, e.Key()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Diff compares two address sets (as returned by Parse) and returns the
+// sorted additions and removals going from prev to next.
+func Diff(prev, next map[string]struct{}) (added, removed []string) {
+	for k := range next {
+		if _, ok := prev[k]; !ok {
+			added = append(added, k)
+		}
+	}
+	for k := range prev {
+		if _, ok := next[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,87 @@
+package sanctions
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func importFile(t *testing.T, format, path string, now time.Time) (string, []Entry, []NameEntry) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	version, entries, names, err := Import(format, f, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return version, entries, names
+}
+
+func nameSummary(names []NameEntry) [][3]any {
+	out := make([][3]any, len(names))
+	for i, n := range names {
+		out[i] = [3]any{n.UID, n.Name, n.DOBs}
+	}
+	return out
+}
+
+func TestImportSDNXML(t *testing.T) {
+	version, entries, names := importFile(t, FormatSDNXML, "testdata/sdn.xml", time.Now())
+	if version != "2024-03-15" {
+		t.Errorf("version = %q, want publish date 2024-03-15", version)
+	}
+	// one address under several chain codes is kept per code; the duplicate
+	// XBT entry keeps the first UID; the empty vessel address is dropped
+	want := []Entry{
+		{Chain: "ETH", Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", UID: "100"},
+		{Chain: "TRX", Address: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", UID: "200"},
+		{Chain: "USDT", Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", UID: "200"},
+		{Chain: "XBT", Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", UID: "100"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v\nwant %+v", entries, want)
+	}
+	wantNames := [][3]any{
+		{"100", "John DOE", []string{"01 Jan 1980"}},
+		{"100", "Johnny DOE", []string{"01 Jan 1980"}},
+		{"200", "ACME EXCHANGE", []string(nil)},
+	}
+	if got := nameSummary(names); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("names = %v, want %v", got, wantNames)
+	}
+}
+
+func TestImportSDNCSV(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 30, 0, 0, time.UTC)
+	version, entries, names := importFile(t, FormatSDNCSV, "testdata/sdn.csv", now)
+	if version != "2024-03-15T123000Z" {
+		t.Errorf("version = %q, want import time", version)
+	}
+	// malformed remarks (no dash, no chain, no address) are skipped
+	want := []Entry{
+		{Chain: "ETH", Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", UID: "100"},
+		{Chain: "ETH", Address: "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359", UID: "300"},
+		{Chain: "TRX", Address: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", UID: "200"},
+		{Chain: "XBT", Address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", UID: "100"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v\nwant %+v", entries, want)
+	}
+	wantNames := [][3]any{
+		{"100", "DOE, John", []string{"01 Jan 1980"}},
+		{"200", "ACME EXCHANGE", []string(nil)},
+	}
+	if got := nameSummary(names); !reflect.DeepEqual(got, wantNames) {
+		t.Errorf("names = %v, want %v", got, wantNames)
+	}
+}
+
+func TestImportUnknownFormat(t *testing.T) {
+	if _, _, _, err := Import("sdn-json", nil, time.Now()); err == nil {
+		t.Fatal("unknown format imported")
+	}
+}
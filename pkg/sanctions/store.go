@@ -0,0 +1,207 @@
+package sanctions
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/christophercampbell/riskr/pkg/config"
+	"github.com/christophercampbell/riskr/pkg/log"
+	"github.com/christophercampbell/riskr/pkg/natsjs"
+	"github.com/christophercampbell/riskr/pkg/policy"
+)
+
+// Registry owns a service's active screening Set and keeps it current with the
+// list versions published to the JetStream object store. Published versions
+// take precedence over the policy's file sources, including across policy
+// reloads. Sets are immutable: a new version makes a new active Set, and the
+// OnSwap callback lets the service recompile its snapshot against it.
+type Registry struct {
+	resolve  func(string) string
+	fallback *List
+
+	mu        sync.Mutex
+	published map[string]*List // latest version per list name from the store
+	active    *Set
+	onSwap    func()
+}
+
+// NewRegistry creates a registry resolving file sources with resolve and using
+// fallback when a policy declares no lists.
+func NewRegistry(resolve func(string) string, fallback *List) *Registry {
+	return &Registry{resolve: resolve, fallback: fallback, published: make(map[string]*List), active: NewSet(fallback)}
+}
+
+// Open is the screening list startup shared by every service: it loads the
+// config list (`sanctions.file`) and the lists declared by p, resolving
+// relative paths against the config root, then follows the object store for
+// published versions. Any list that cannot be loaded fails startup; a service
+// must not screen against a silently empty list.
+func Open(ctx context.Context, cfg *config.Config, p *policy.Policy, js nats.JetStreamContext, logger log.Logger) (*Registry, error) {
+	var fallback *List
+	if cfg.Sanctions.File != "" {
+		l, err := LoadFile(DefaultList, cfg.ResolvePath(cfg.Sanctions.File))
+		if err != nil {
+			return nil, fmt.Errorf("sanctions list %s: %w", DefaultList, err)
+		}
+		fallback = l
+	}
+	r := NewRegistry(cfg.ResolvePath, fallback)
+	s, err := r.Apply(p.Lists)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range s.Lists() {
+		if l.Len() == 0 {
+			logger.Warn("sanctions list is empty", "list", l.Name, "source", l.Source)
+		}
+		logger.Info("sanctions list loaded", "list", l.Name, "version", l.Version, "entries", l.Len(), "invalid", l.Invalid)
+	}
+	obs, err := natsjs.ObjectStore(js, natsjs.BucketSanctions)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.Watch(ctx, obs, logger); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Apply prepares the set for defs and makes it active.
+func (r *Registry) Apply(defs []policy.ListDef) (*Set, error) {
+	s, err := r.Prepare(defs)
+	if err != nil {
+		return nil, err
+	}
+	r.Commit(s)
+	return r.Active(), nil
+}
+
+// Prepare builds the set for defs with published versions overlaid, without
+// making it active. A published version that conflicts with a policy pin is
+// not used. It fails when defs cannot be loaded or nothing would be screened.
+func (r *Registry) Prepare(defs []policy.ListDef) (*Set, error) {
+	s, err := Build(defs, r.resolve, r.fallback)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Lists()) == 0 {
+		return nil, fmt.Errorf("no screening lists: policy declares none and config sanctions.file is not set")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.overlayLocked(s), nil
+}
+
+// Commit makes a prepared set active, with versions published since Prepare
+// overlaid; the swaps that published them call OnSwap to recompile.
+func (r *Registry) Commit(s *Set) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.active = r.overlayLocked(s)
+}
+
+func (r *Registry) overlayLocked(s *Set) *Set {
+	for _, l := range r.published {
+		if ns, _ := s.with(l); ns != nil {
+			s = ns
+		}
+	}
+	return s
+}
+
+// OnSwap sets fn to be called after a published version replaced the active
+// set. A version swapped in before fn is set does not call it.
+func (r *Registry) OnSwap(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onSwap = fn
+}
+
+// Active returns the active set.
+func (r *Registry) Active() *Set {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active
+}
+
+// Swap records l as the latest version of its list and makes a new active set
+// with it, reporting whether the active set references the list. Lists the
+// policy does not reference are kept for later reloads.
+func (r *Registry) Swap(l *List) (bool, error) {
+	r.mu.Lock()
+	r.published[l.Name] = l
+	ns, err := r.active.with(l)
+	if ns != nil {
+		r.active = ns
+	}
+	fn := r.onSwap
+	r.mu.Unlock()
+	if ns != nil && fn != nil {
+		fn()
+	}
+	return ns != nil, err
+}
+
+// Watch follows the object store and hot-swaps list versions until ctx ends.
+// The current version of every stored list is delivered first.
+func (r *Registry) Watch(ctx context.Context, obs nats.ObjectStore, logger log.Logger) error {
+	w, err := obs.Watch(nats.IgnoreDeletes())
+	if err != nil {
+		return err
+	}
+	go func() {
+		defer w.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case info, ok := <-w.Updates():
+				if !ok {
+					return
+				}
+				if info == nil { // initial values delivered
+					continue
+				}
+				b, err := obs.GetBytes(info.Name)
+				if err != nil {
+					logger.Error("sanctions watch: get", "list", info.Name, "err", err)
+					continue
+				}
+				l, err := read(info.Name, objectSource(info.Name), bytes.NewReader(b))
+				if err != nil {
+					logger.Error("sanctions watch: parse", "list", info.Name, "err", err)
+					continue
+				}
+				swapped, err := r.Swap(l)
+				if err != nil {
+					logger.Error("sanctions watch: swap", "list", l.Name, "err", err)
+					continue
+				}
+				logger.Info("sanctions list update", "list", l.Name, "version", l.Version, "entries", l.Len(), "active", swapped)
+			}
+		}
+	}()
+	return nil
+}
+
+// Publish validates data as a list file and stores it as the current version
+// of the named list.
+func Publish(obs nats.ObjectStore, name string, data []byte) (*List, error) {
+	l, err := read(name, objectSource(name), bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	meta := &nats.ObjectMeta{Name: name, Description: "screening list " + name, Metadata: map[string]string{"version": l.Version}}
+	if _, err := obs.Put(meta, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func objectSource(name string) string {
+	return fmt.Sprintf("nats://%s/%s", natsjs.BucketSanctions, name)
+}
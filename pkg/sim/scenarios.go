@@ -23,6 +23,7 @@ const (
 	OFAC        = "ofac"
 	DAILY       = "daily"
 	STRUCTURING = "structuring"
+	PASSTHROUGH = "passthrough"
 )
 
 var (
@@ -31,6 +32,7 @@ var (
 		OFAC:        {},
 		DAILY:       {},
 		STRUCTURING: {},
+		PASSTHROUGH: {},
 	}
 )
 
@@ -64,6 +66,8 @@ func Run(ctx context.Context, cfg *config.Config, logger log.Logger, scenario st
 		return simDaily(ctx, nc, logger)
 	case "structuring":
 		return simStructuring(ctx, nc, logger)
+	case "passthrough":
+		return simPassThrough(ctx, nc, logger)
 	default:
 		return fmt.Errorf("unknown scenario %s", scenario)
 	}
@@ -71,18 +75,18 @@ func Run(ctx context.Context, cfg *config.Config, logger log.Logger, scenario st
 
 func simClean(ctx context.Context, nc *nats.Conn, logger log.Logger) error {
 	logger.Info("sim clean")
-	return pubTx(nc, logger, "U1", "A1", []string{"0xClean"}, "USDC", "1000000", 1.00)
+	return pubTx(nc, logger, "U1", "A1", []string{"0xClean"}, "USDC", "1000000", 1.00, "inbound")
 }
 
 func simOFAC(ctx context.Context, nc *nats.Conn, logger log.Logger) error {
 	logger.Info("sim ofac")
-	return pubTx(nc, logger, "U2", "A2", []string{"0x000000000000000000000000000000000000dEaD"}, "USDC", "1000000", 1.00)
+	return pubTx(nc, logger, "U2", "A2", []string{"0x000000000000000000000000000000000000dEaD"}, "USDC", "1000000", 1.00, "inbound")
 }
 
 func simDaily(ctx context.Context, nc *nats.Conn, logger log.Logger) error {
 	logger.Info("sim daily limit breach")
 	for i := 0; i < 6; i++ { // 6 * 10k = 60k > 50k
-		if err := pubTx(nc, logger, "U3", "A3", []string{fmt.Sprintf("0xU3%02d", i)}, "USDC", "10000000", 10000.00); err != nil {
+		if err := pubTx(nc, logger, "U3", "A3", []string{fmt.Sprintf("0xU3%02d", i)}, "USDC", "10000000", 10000.00, "inbound"); err != nil {
 			return err
 		}
 	}
@@ -92,14 +96,23 @@ func simDaily(ctx context.Context, nc *nats.Conn, logger log.Logger) error {
 func simStructuring(ctx context.Context, nc *nats.Conn, logger log.Logger) error {
 	logger.Info("sim structuring")
 	for i := 0; i < 6; i++ { // 6 * $5k deposits triggers R5 cnt>5 (<10k threshold)
-		if err := pubTx(nc, logger, "U4", "A4", []string{fmt.Sprintf("0xU4%02d", i)}, "USDC", "5000000", 5000.00); err != nil {
+		if err := pubTx(nc, logger, "U4", "A4", []string{fmt.Sprintf("0xU4%02d", i)}, "USDC", "5000000", 5000.00, "inbound"); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func pubTx(nc *nats.Conn, _ log.Logger, user, acct string, addrs []string, asset, amount string, usd float64) error {
+func simPassThrough(ctx context.Context, nc *nats.Conn, logger log.Logger) error {
+	logger.Info("sim pass-through")
+	// $20k in, then $19.5k out shortly after => ratio 0.975 >= 0.9
+	if err := pubTx(nc, logger, "U5", "A5", []string{"0xU500"}, "USDC", "20000000", 20000.00, "inbound"); err != nil {
+		return err
+	}
+	return pubTx(nc, logger, "U5", "A5", []string{"0xU500"}, "USDC", "19500000", 19500.00, "outbound")
+}
+
+func pubTx(nc *nats.Conn, _ log.Logger, user, acct string, addrs []string, asset, amount string, usd float64, dir string) error {
 	te := events.TxEvent{
 		SchemaVersion: events.SchemaVersion,
 		EventID:       randID(),
@@ -108,7 +121,7 @@ func pubTx(nc *nats.Conn, _ log.Logger, user, acct string, addrs []string, asset
 		Subject:       events.Subject{UserID: user, AccountID: acct, Addresses: addrs, GeoISO: "US", KYCTier: "L2"},
 		Chain:         "SIM",
 		TxHash:        randID(),
-		Direction:     "inbound",
+		Direction:     dir,
 		Asset:         asset,
 		Amount:        amount,
 		USDValue:      decimal.NewFromFloat(usd).String(),
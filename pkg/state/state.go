@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/christophercampbell/riskr/pkg/address"
 	"github.com/shopspring/decimal"
 )
 
@@ -14,12 +15,94 @@ type View interface {
 	RollingUSD24h(user string) decimal.Decimal
 	RollingSmallCnt24h(user string, amtThresh decimal.Decimal) int64
 	AddTx(user string, at time.Time, usd decimal.Decimal)
+	// AddFlow records a directional transfer for the user.
+	AddFlow(user string, f Flow)
+	// Flows returns the user's transfers observed within window of now (max 24h), oldest first.
+	Flows(user string, now time.Time, window time.Duration) []Flow
+	// DistinctCounterparties counts distinct counterparty addresses seen within window
+	// in the given direction ("" for both), in transfers of at least minUSD, and
+	// returns up to sample of them.
+	DistinctCounterparties(user string, now time.Time, window time.Duration, direction string, minUSD decimal.Decimal, sample int) (int64, []string)
+	// Destination returns the user's history with an outbound destination address.
+	Destination(user, chain, addr string) (AddrUse, bool)
+	// InEdges returns the value transfers observed into addr (transaction graph)
+	// within EdgeTTL, at most MaxInEdges of them, oldest first.
+	InEdges(chain, addr string) []Edge
+}
+
+// Transaction graph bounds: edges expire after EdgeTTL, and each address
+// keeps at most its MaxInEdges newest inbound edges.
+const (
+	EdgeTTL    = 30 * 24 * time.Hour
+	MaxInEdges = 1000
+
+	edgeSweepEvery = 10_000 // edge inserts between sweeps of idle addresses
+)
+
+// Edge is a directed value transfer between two addresses in the local
+// transaction graph. It is not subject to the 24h window, see EdgeTTL.
+type Edge struct {
+	Chain   string
+	From    string
+	To      string
+	USD     decimal.Decimal
+	At      time.Time
+	EventID string
+	TxHash  string
+}
+
+// AddrUse tracks when a user first sent to a destination address and how often.
+// It is not subject to the 24h window.
+type AddrUse struct {
+	FirstSeen    time.Time
+	FirstEventID string
+	Uses         int64
+}
+
+// Flow is a single directional value movement attributed to a user.
+type Flow struct {
+	EventID   string
+	At        time.Time
+	Direction string // inbound|outbound
+	USD       decimal.Decimal
+	// Chain is the chain of the addresses below (optional, see address.Normalize).
+	Chain string
+	// Counterparty is the address on the other side (optional).
+	Counterparty string
+	// Address is the user's own address involved (optional); with Counterparty
+	// it feeds the transaction graph.
+	Address string
+	// TxHash identifies the on-chain transfer so both sides of an internal
+	// transfer map to a single graph edge (optional).
+	TxHash string
 }
 
 type memView struct {
 	mu sync.Mutex
 	// per user list of entries (timestamp, usd)
 	entries map[string][]entry
+	// per user list of directional flows
+	flows map[string][]Flow
+	// per user destination address book, keyed by address key
+	dests map[string]map[string]*AddrUse
+	// transaction graph: destination address key -> inbound edges,
+	// oldest first, and the identities of the edges held, for dedupe
+	inEdges  map[string][]Edge
+	edgeKeys map[edgeKey]struct{}
+	edgeAdds int
+}
+
+// addrKey is the key addresses are stored under, so that the forms of one
+// address (e.g. EIP-55 and lower-case hex) meet and distinct case-sensitive
+// ones (base58) stay apart.
+func addrKey(chain, addr string) string {
+	k, _ := address.Normalize(chain, addr)
+	return k.String()
+}
+
+// edgeKey identifies a transfer into To; id is its event ID or tx hash.
+type edgeKey struct {
+	to, from, id string
 }
 
 type entry struct {
@@ -27,7 +110,15 @@ type entry struct {
 	usd decimal.Decimal
 }
 
-func NewMem() View { return &memView{entries: make(map[string][]entry)} }
+func NewMem() View {
+	return &memView{
+		entries:  make(map[string][]entry),
+		flows:    make(map[string][]Flow),
+		dests:    make(map[string]map[string]*AddrUse),
+		inEdges:  make(map[string][]Edge),
+		edgeKeys: make(map[edgeKey]struct{}),
+	}
+}
 
 func (m *memView) pruneLocked(u string, now time.Time) {
 	cut := now.Add(-24 * time.Hour)
@@ -41,6 +132,16 @@ func (m *memView) pruneLocked(u string, now time.Time) {
 	if idx > 0 {
 		m.entries[u] = append([]entry(nil), s[idx:]...)
 	}
+	f := m.flows[u]
+	idx = 0
+	for ; idx < len(f); idx++ {
+		if f[idx].At.After(cut) {
+			break
+		}
+	}
+	if idx > 0 {
+		m.flows[u] = append([]Flow(nil), f[idx:]...)
+	}
 }
 
 func (m *memView) RollingUSD24h(u string) decimal.Decimal {
@@ -75,3 +176,146 @@ func (m *memView) AddTx(u string, at time.Time, usd decimal.Decimal) {
 	defer m.mu.Unlock()
 	m.entries[u] = append(m.entries[u], entry{ts: at, usd: usd})
 }
+
+func (m *memView) AddFlow(u string, f Flow) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// flows past the window go as new ones arrive, so a user that is never
+	// queried (the gateway only reads destinations) stays bounded
+	m.pruneLocked(u, time.Now())
+	m.flows[u] = append(m.flows[u], f)
+	if f.Address != "" && f.Counterparty != "" {
+		e := Edge{Chain: f.Chain, From: f.Counterparty, To: f.Address, USD: f.USD, At: f.At, EventID: f.EventID, TxHash: f.TxHash}
+		if f.Direction == "outbound" {
+			e.From, e.To = f.Address, f.Counterparty
+		}
+		m.addEdgeLocked(e)
+	}
+	if f.Direction != "outbound" || f.Counterparty == "" {
+		return
+	}
+	book := m.dests[u]
+	if book == nil {
+		book = make(map[string]*AddrUse)
+		m.dests[u] = book
+	}
+	k := addrKey(f.Chain, f.Counterparty)
+	if use, ok := book[k]; ok {
+		use.Uses++
+		return
+	}
+	book[k] = &AddrUse{FirstSeen: f.At, FirstEventID: f.EventID, Uses: 1}
+}
+
+func (m *memView) Flows(u string, now time.Time, window time.Duration) []Flow {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneLocked(u, now)
+	cut := now.Add(-window)
+	var out []Flow
+	for _, f := range m.flows[u] {
+		if f.At.After(cut) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func (m *memView) DistinctCounterparties(u string, now time.Time, window time.Duration, direction string, minUSD decimal.Decimal, sample int) (int64, []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneLocked(u, now)
+	cut := now.Add(-window)
+	seen := make(map[string]struct{})
+	var addrs []string
+	for _, f := range m.flows[u] {
+		if f.Counterparty == "" || !f.At.After(cut) {
+			continue
+		}
+		if direction != "" && f.Direction != direction || f.USD.LessThan(minUSD) {
+			continue
+		}
+		k := addrKey(f.Chain, f.Counterparty)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		if len(addrs) < sample {
+			addrs = append(addrs, f.Counterparty)
+		}
+	}
+	return int64(len(seen)), addrs
+}
+
+func (m *memView) Destination(u, chain, addr string) (AddrUse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	use, ok := m.dests[u][addrKey(chain, addr)]
+	if !ok {
+		return AddrUse{}, false
+	}
+	return *use, true
+}
+
+// addEdgeLocked appends e unless the same transfer is already in the graph
+// (e.g. recorded from both the sender's and the receiver's side), then
+// expires and caps the edges into e.To. Every edgeSweepEvery inserts, all
+// addresses are swept, so idle ones expire too.
+func (m *memView) addEdgeLocked(e Edge) {
+	to, from := addrKey(e.Chain, e.To), addrKey(e.Chain, e.From)
+	byEvent, byTx := edgeKey{to, from, "e:" + e.EventID}, edgeKey{to, from, "t:" + e.TxHash}
+	if _, ok := m.edgeKeys[byEvent]; ok {
+		return
+	}
+	if _, ok := m.edgeKeys[byTx]; ok && e.TxHash != "" {
+		return
+	}
+	m.edgeKeys[byEvent] = struct{}{}
+	if e.TxHash != "" {
+		m.edgeKeys[byTx] = struct{}{}
+	}
+	edges := append(m.inEdges[to], e)
+	// keep edges ordered by time; transfers mostly arrive in order
+	for i := len(edges) - 1; i > 0 && edges[i].At.Before(edges[i-1].At); i-- {
+		edges[i], edges[i-1] = edges[i-1], edges[i]
+	}
+	m.inEdges[to] = edges
+	now := time.Now()
+	m.pruneEdgesLocked(to, now)
+	if m.edgeAdds++; m.edgeAdds%edgeSweepEvery == 0 {
+		for addr := range m.inEdges {
+			m.pruneEdgesLocked(addr, now)
+		}
+	}
+}
+
+// pruneEdgesLocked drops the edges into to older than EdgeTTL and the oldest
+// beyond MaxInEdges.
+func (m *memView) pruneEdgesLocked(to string, now time.Time) {
+	edges := m.inEdges[to]
+	cut := now.Add(-EdgeTTL)
+	n := 0
+	for n < len(edges) && (edges[n].At.Before(cut) || len(edges)-n > MaxInEdges) {
+		x := edges[n]
+		from := addrKey(x.Chain, x.From)
+		delete(m.edgeKeys, edgeKey{to, from, "e:" + x.EventID})
+		if x.TxHash != "" {
+			delete(m.edgeKeys, edgeKey{to, from, "t:" + x.TxHash})
+		}
+		n++
+	}
+	switch {
+	case n == len(edges):
+		delete(m.inEdges, to)
+	case n > 0:
+		m.inEdges[to] = append([]Edge(nil), edges[n:]...)
+	}
+}
+
+func (m *memView) InEdges(chain, addr string) []Edge {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := addrKey(chain, addr)
+	m.pruneEdgesLocked(k, time.Now())
+	return append([]Edge(nil), m.inEdges[k]...)
+}
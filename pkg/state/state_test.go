@@ -0,0 +1,65 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestInEdgesDedupeCapAndExpiry(t *testing.T) {
+	m := NewMem()
+	now := time.Now()
+	in := func(id, tx string, at time.Time) Flow {
+		return Flow{EventID: id, TxHash: tx, At: at, Direction: "inbound", USD: decimal.NewFromInt(1), Address: "0xTo", Counterparty: "0xFrom"}
+	}
+	m.AddFlow("u", in("e1", "tx1", now))
+	// the sender's side of the same transfer
+	m.AddFlow("v", Flow{EventID: "e2", TxHash: "tx1", At: now, Direction: "outbound", USD: decimal.NewFromInt(1), Address: "0xfrom", Counterparty: "0xto"})
+	m.AddFlow("u", in("e1", "", now))
+	if got := len(m.InEdges("", "0xto")); got != 1 {
+		t.Fatalf("edges = %d, want 1 after duplicates", got)
+	}
+
+	m.AddFlow("u", in("old", "", now.Add(-EdgeTTL-time.Hour)))
+	if got := len(m.InEdges("", "0xto")); got != 1 {
+		t.Fatalf("edges = %d, want expired edge dropped", got)
+	}
+
+	for i := range MaxInEdges + 10 {
+		m.AddFlow("u", in(fmt.Sprint(i), "", now.Add(time.Duration(i)*time.Second)))
+	}
+	edges := m.InEdges("", "0xto")
+	if len(edges) != MaxInEdges {
+		t.Fatalf("edges = %d, want cap %d", len(edges), MaxInEdges)
+	}
+	if edges[0].EventID != "10" || edges[len(edges)-1].EventID != fmt.Sprint(MaxInEdges+9) {
+		t.Fatalf("kept %s..%s, want the newest", edges[0].EventID, edges[len(edges)-1].EventID)
+	}
+	// a capped-out edge is no longer a duplicate
+	m.AddFlow("u", in("e1", "tx1", now.Add(time.Hour)))
+	if got := m.InEdges("", "0xto"); got[len(got)-1].EventID != "e1" {
+		t.Fatalf("re-added edge missing")
+	}
+}
+
+func TestAddressesKeyedPerChain(t *testing.T) {
+	m := NewMem()
+	out := func(chain, addr string) Flow {
+		return Flow{EventID: addr, At: time.Now(), Direction: "outbound", USD: decimal.NewFromInt(1), Chain: chain, Counterparty: addr}
+	}
+	// EIP-55 and lower-case forms of one EVM address meet
+	m.AddFlow("u", out("ETH", "0x000000000000000000000000000000000000dEaD"))
+	if _, ok := m.Destination("u", "ETH", "0x000000000000000000000000000000000000dead"); !ok {
+		t.Fatal("lower-case form not found")
+	}
+	// base58 is case-sensitive: these are two addresses
+	m.AddFlow("u", out("SOL", "So11111111111111111111111111111111111111112"))
+	if _, ok := m.Destination("u", "SOL", "so11111111111111111111111111111111111111112"); ok {
+		t.Fatal("base58 address matched case-insensitively")
+	}
+	if n, _ := m.DistinctCounterparties("u", time.Now(), time.Hour, "", decimal.Zero, 0); n != 2 {
+		t.Fatalf("counterparties = %d, want 2", n)
+	}
+}
@@ -2,12 +2,10 @@ package streamer
 
 import (
 	"context"
-	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"os"
-	"strings"
 	"time"
 
 	nats "github.com/nats-io/nats.go"
@@ -19,11 +17,22 @@ import (
 	"github.com/christophercampbell/riskr/pkg/natsjs"
 	"github.com/christophercampbell/riskr/pkg/policy"
 	"github.com/christophercampbell/riskr/pkg/rules"
+	"github.com/christophercampbell/riskr/pkg/sanctions"
 	"github.com/christophercampbell/riskr/pkg/state"
 )
 
 const (
 	connName = "riskr-streamer"
+
+	// txRedeliverDelay is how long a tx waits for redelivery when its
+	// decision could not be persisted.
+	txRedeliverDelay = 5 * time.Second
+	// txAckWait and txMaxAckPending configure the tx consumer; a publish
+	// with retries takes up to ~6s, well within the ack wait.
+	txAckWait       = 30 * time.Second
+	txMaxAckPending = 1024
+	// maxApplied bounds the tx events remembered as applied to state.
+	maxApplied = 100_000
 )
 
 func durableGroupName(task string) string {
@@ -31,12 +40,21 @@ func durableGroupName(task string) string {
 }
 
 type Worker struct {
-	cfg           *config.Config
-	log           log.Logger
-	nc            *nats.Conn
-	state         state.View
-	rulez         []rules.Rule
-	policyVersion string
+	cfg    *config.Config
+	log    log.Logger
+	nc     *nats.Conn
+	js     nats.JetStreamContext
+	state  state.View
+	active rules.Active // policy, lists and rules; replaced whole on reload
+
+	// tx events already applied to state, so a redelivered tx is not counted
+	// twice; only touched from the tx subscription callback
+	applied     map[string]struct{}
+	appliedRing []string
+	appliedNext int
+	// override decisions computed but not yet persisted, by event ID; a
+	// redelivered tx publishes its entry instead of being re-evaluated
+	unsent map[string]*events.DecisionEvent
 }
 
 func Run(ctx context.Context, cfg *config.Config, logger log.Logger) error {
@@ -49,24 +67,35 @@ func Run(ctx context.Context, cfg *config.Config, logger log.Logger) error {
 	if err != nil {
 		return err
 	}
-	if err = natsjs.Bootstrap(js); err != nil {
+	if err = natsjs.Bootstrap(js, cfg.HTTP.IdempotencyTTL()); err != nil {
 		return err
 	}
 
-	// load sanctions + policy (same as gateway for now)
+	// load policy + screening lists (shared with the gateway)
 	p, err := policy.LoadFile(cfg.ResolvePolicyFile())
 	if err != nil {
 		return err
 	}
-	sanctions, _ := loadSanctions(cfg.Sanctions.File) // ignore err for now
+	reg, err := sanctions.Open(ctx, cfg, p, js, logger)
+	if err != nil {
+		return err
+	}
 
 	w := &Worker{
-		cfg:           cfg,
-		log:           logger,
-		nc:            nc,
-		state:         state.NewMem(),
-		rulez:         rules.BuildRules(p, sanctions, p.Params),
-		policyVersion: p.Version,
+		cfg:     cfg,
+		log:     logger,
+		nc:      nc,
+		js:      js,
+		state:   state.NewMem(),
+		applied: make(map[string]struct{}),
+		unsent:  make(map[string]*events.DecisionEvent),
+	}
+	// a published list version makes a new snapshot with the current policy
+	err = w.active.Follow(p, reg, func(err error) {
+		logger.Error("policy recompile on list update", "err", err)
+	})
+	if err != nil {
+		return fmt.Errorf("policy %s: %w", p.Version, err)
 	}
 
 	// subscribe to policy apply
@@ -74,31 +103,42 @@ func Run(ctx context.Context, cfg *config.Config, logger log.Logger) error {
 	logger.Info("subscribing", "subject", natsjs.SubjPolicyApply, "group", policyApplyGroup)
 	policyApplySub, err := natsjs.SubscribeDurable(ctx, js, natsjs.SubjPolicyApply, policyApplyGroup, true, func(m *nats.Msg) {
 		var np policy.Policy
-		if err = json.Unmarshal(m.Data, &np); err != nil {
+		if err := json.Unmarshal(m.Data, &np); err != nil {
 			logger.Error("policy sub", "err", err)
 			return
 		}
 		logger.Info("policy update", "ver", np.Version)
-		w.rulez = rules.BuildRules(&np, sanctions, np.Params)
-		w.policyVersion = np.Version
-		// w.handlePolicyApply ...
+		if err := w.active.Apply(&np, reg); err != nil {
+			logger.Error("policy lists", "ver", np.Version, "err", err)
+		}
 	})
 	if err != nil {
 		return err
 	}
 	defer policyApplySub.Unsubscribe()
 
-	// subscribe to tx events
+	// subscribe to tx events; a tx is acked only once its decision is
+	// persisted, otherwise it is redelivered. The consumer is created here, not
+	// by the subscription, so unacked txs survive a restart.
 	txGroup := durableGroupName("tx-process")
 	logger.Info("subscribing", "subject", natsjs.SubjTxEvent, "group", txGroup)
-	txSub, err := natsjs.SubscribeDurable(ctx, js, natsjs.SubjTxEvent, txGroup, true,
+	if err := natsjs.EnsureDurableConsumer(js, natsjs.StreamEvents, txGroup, natsjs.SubjTxEvent, txAckWait, txMaxAckPending); err != nil {
+		return err
+	}
+	txSub, err := natsjs.SubscribeBound(ctx, js, natsjs.StreamEvents, txGroup, natsjs.SubjTxEvent,
 		func(m *nats.Msg) {
 			var te events.TxEvent
-			if err = te.Unmarshal(m.Data); err != nil {
+			if err := te.Unmarshal(m.Data); err != nil {
 				logger.Error("tx unmarshal", "err", err)
+				_ = m.Term()
 				return
 			}
-			w.handleTx(&te)
+			if err := w.handleTx(ctx, &te); err != nil {
+				logger.Error("tx decision not persisted, redelivering", "event", te.EventID, "err", err)
+				_ = m.NakWithDelay(txRedeliverDelay)
+				return
+			}
+			_ = m.Ack()
 		})
 	if err != nil {
 		return err
@@ -106,11 +146,15 @@ func Run(ctx context.Context, cfg *config.Config, logger log.Logger) error {
 	defer txSub.Unsubscribe()
 
 	// subscribe to provisional decisions
-	provDecGroup := durableGroupName("prov-dec")
-	logger.Info("subscribing", "subject", natsjs.SubjDecisionProv, "group", provDecGroup)
-	decProvSub, err := natsjs.SubscribeDurable(ctx, js, natsjs.SubjDecisionProv, provDecGroup, true, func(m *nats.Msg) {
+	provDecGroup, provDecSubj := durableGroupName("prov-dec"), natsjs.SubjDecisionProv+".>"
+	logger.Info("subscribing", "subject", provDecSubj, "group", provDecGroup)
+	if err := natsjs.EnsureDurableConsumer(js, natsjs.StreamDecisions, provDecGroup, provDecSubj, txAckWait, txMaxAckPending); err != nil {
+		return err
+	}
+	decProvSub, err := natsjs.SubscribeBound(ctx, js, natsjs.StreamDecisions, provDecGroup, provDecSubj, func(m *nats.Msg) {
+		defer m.Ack()
 		var de events.DecisionEvent
-		if err = de.Unmarshal(m.Data); err != nil {
+		if err := de.Unmarshal(m.Data); err != nil {
 			logger.Error("prov unmarshal", "err", err)
 			return
 		}
@@ -125,65 +169,93 @@ func Run(ctx context.Context, cfg *config.Config, logger log.Logger) error {
 	return nil
 }
 
-func (w *Worker) handleTx(te *events.TxEvent) {
+// handleTx applies te to state, runs the streaming rules and publishes an
+// override decision if they escalate. It returns an error only when that
+// decision was not persisted; it is then kept, and a redelivery of te
+// retries its publish rather than re-evaluating against newer state.
+func (w *Worker) handleTx(ctx context.Context, te *events.TxEvent) error {
+	if de, ok := w.unsent[te.EventID]; ok {
+		return w.publishOverride(ctx, te.EventID, de)
+	}
 	if msg, err := te.Marshal(); err != nil {
 		w.log.Error("failed to handle tx", "err", err)
-		return
+		return nil
 	} else {
 		w.log.Info("handling transaction", "tx", string(msg))
 	}
+	if w.markApplied(te.EventID) {
+		w.applyTx(te)
+	}
+
+	snap := w.active.Load()
+	out := snap.EvalStreaming(context.Background(), time.Now(), te, w.state)
+	final, evv := out.Decision, out.Evidence
+	if final == decision.Allow {
+		return nil
+	}
+
+	// the ID is derived from the event so a redelivered tx re-publishes the
+	// same decision and JetStream drops the duplicate
+	de := &events.DecisionEvent{SchemaVersion: events.SchemaVersion, DecisionID: overrideID(te.EventID), EventID: te.EventID, UserID: te.Subject.UserID, IssuedAt: time.Now(), Stage: "override", Decision: final, DecisionCode: pickCode(final, evv), PolicyVersion: snap.Version, Evidence: evv, ClientID: te.ClientID}
+	return w.publishOverride(ctx, te.EventID, de)
+}
+
+func (w *Worker) publishOverride(ctx context.Context, eventID string, de *events.DecisionEvent) error {
+	if err := natsjs.PublishJSON(ctx, w.js, natsjs.DecisionSubject(natsjs.SubjDecisionFinal, de.UserID), de.DecisionID, de); err != nil {
+		w.unsent[eventID] = de
+		return err
+	}
+	delete(w.unsent, eventID)
+	w.log.Info("stream override", "user", de.UserID, "decision", de.Decision)
+	return nil
+}
+
+// markApplied records eventID and reports whether it was new.
+func (w *Worker) markApplied(eventID string) bool {
+	if _, ok := w.applied[eventID]; ok {
+		return false
+	}
+	if len(w.appliedRing) < maxApplied {
+		w.appliedRing = append(w.appliedRing, eventID)
+	} else {
+		delete(w.applied, w.appliedRing[w.appliedNext])
+		w.appliedRing[w.appliedNext] = eventID
+		w.appliedNext = (w.appliedNext + 1) % maxApplied
+	}
+	w.applied[eventID] = struct{}{}
+	return true
+}
+
+func (w *Worker) applyTx(te *events.TxEvent) {
 	usd := te.USDDecimal()
 	// update state for streaming rules
 	w.state.AddTx(te.Subject.UserID, te.OccurredAt, usd)
+	flow := state.Flow{EventID: te.EventID, At: te.OccurredAt, Direction: te.Direction, USD: usd, Chain: te.Chain, Counterparty: te.Counterparty, Address: graphAddress(te), TxHash: te.TxHash}
+	w.state.AddFlow(te.Subject.UserID, flow)
+}
 
-	final := decision.Allow
-	var evv []events.Evidence
-	now := time.Now()
-	for _, rl := range w.rulez {
-		if hit, dec, ev := rl.EvalStreaming(now, te, w.state); hit {
-			final = decision.Max(final, dec)
-			if ev.RuleID != "" {
-				evv = append(evv, ev)
-			}
-		}
+// graphAddress is the subject address the transfer moved through: the one the
+// event names, else the subject's only address. With several addresses and
+// none named, the side is unknown and the transfer adds no graph edge.
+func graphAddress(te *events.TxEvent) string {
+	if te.Address != "" {
+		return te.Address
 	}
-
-	if final != decision.Allow {
-		de := events.DecisionEvent{SchemaVersion: events.SchemaVersion, DecisionID: randID(), EventID: te.EventID, IssuedAt: time.Now(), Stage: "override", Decision: final, DecisionCode: pickCode(final, evv), PolicyVersion: w.policyVersion, Evidence: evv}
-		if b, err := de.Marshal(); err == nil {
-			_ = w.nc.Publish(natsjs.SubjDecisionFinal, b)
-		}
-		w.log.Info("stream override", "user", te.Subject.UserID, "decision", final)
+	if len(te.Subject.Addresses) == 1 {
+		return te.Subject.Addresses[0]
 	}
+	return ""
 }
 
 // local copy of gateway helpers (could refactor common)
 func pickCode(dec string, ev []events.Evidence) string {
-	if len(ev) == 0 {
+	if len(ev) == 0 || dec == decision.Allow {
 		return "OK"
 	}
 	return ev[0].RuleID
 }
 
-func randID() string {
-	b := make([]byte, 8)
-	_, _ = rand.Read(b)
-	return hex.EncodeToString(b)
-}
-
-func loadSanctions(path string) (map[string]struct{}, error) {
-	b, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	m := make(map[string]struct{})
-	lines := strings.Split(string(b), "\n")
-	for _, ln := range lines {
-		ln = strings.TrimSpace(ln)
-		if ln == "" || strings.HasPrefix(ln, "#") {
-			continue
-		}
-		m[strings.ToLower(ln)] = struct{}{}
-	}
-	return m, nil
+func overrideID(eventID string) string {
+	h := sha256.Sum256([]byte(eventID + "/override"))
+	return hex.EncodeToString(h[:8])
 }
@@ -0,0 +1,54 @@
+package streamer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/state"
+)
+
+// Graph edges go to the subject address the transfer moved through, not to
+// the subject's first address.
+func TestApplyTxEdgeAddress(t *testing.T) {
+	const (
+		a1  = "0x00000000000000000000000000000000000000a1"
+		a2  = "0x00000000000000000000000000000000000000a2"
+		src = "0x00000000000000000000000000000000000000cc"
+	)
+	for _, tc := range []struct {
+		name      string
+		addrs     []string
+		address   string
+		want      string // address holding the edge, empty for none
+		direction string
+	}{
+		{"named receiving address", []string{a1, a2}, a2, a2, "inbound"},
+		{"named sending address", []string{a1, a2}, a2, a2, "outbound"},
+		{"only address", []string{a1}, "", a1, "inbound"},
+		{"unknown side", []string{a1, a2}, "", "", "inbound"},
+	} {
+		w := &Worker{state: state.NewMem()}
+		w.applyTx(&events.TxEvent{EventID: "e1", OccurredAt: time.Now(), Chain: "ETH", Direction: tc.direction, Counterparty: src, Address: tc.address, USDValue: "100",
+			Subject: events.Subject{UserID: "u", Addresses: tc.addrs}})
+		for _, a := range tc.addrs {
+			edges := w.state.InEdges("ETH", a)
+			if tc.direction == "outbound" {
+				edges = w.state.InEdges("ETH", src)
+			}
+			got := 0
+			for _, e := range edges {
+				if e.To == a || e.From == a {
+					got++
+				}
+			}
+			want := 0
+			if a == tc.want {
+				want = 1
+			}
+			if got != want {
+				t.Errorf("%s: %d edges through %s, want %d", tc.name, got, a, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package webhook delivers decisions from the DECISIONS stream to HTTP
+// endpoints for services that do not speak NATS.
+//
+// Each endpoint has its own durable consumer, so a slow or failing endpoint
+// neither blocks the others nor loses its position across restarts. Failed
+// deliveries are redelivered by JetStream with exponential backoff; once an
+// endpoint's attempts are exhausted (or it rejects a payload outright) the
+// decision is parked on the dead-letter stream.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/christophercampbell/riskr/pkg/config"
+	"github.com/christophercampbell/riskr/pkg/events"
+	"github.com/christophercampbell/riskr/pkg/log"
+	"github.com/christophercampbell/riskr/pkg/natsjs"
+)
+
+const connName = "riskr-webhook"
+
+// maxAckPending bounds the decisions in flight (delivering or waiting for a
+// retry) per endpoint.
+const maxAckPending = 1024
+
+// Headers sent with every delivery. The signature is
+// `t=<unix seconds>,v1=<hex HMAC-SHA256(secret, "<t>.<body>")>`; receivers
+// should recompute it and reject stale timestamps.
+const (
+	HeaderSignature  = "X-Riskr-Signature"
+	HeaderDecisionID = "X-Riskr-Decision-Id" // stable across retries, for receiver dedupe
+	HeaderAttempt    = "X-Riskr-Attempt"
+)
+
+// defaults for unset config values
+const (
+	defaultMaxAttempts = 8
+	defaultBackoff     = time.Second
+	defaultMaxBackoff  = time.Minute
+	defaultTimeout     = 5 * time.Second
+)
+
+// defaultStages are delivered when an endpoint does not filter by stage:
+// provisional decisions are already returned synchronously by the gateway.
+var defaultStages = []string{"final", "override"}
+
+type endpoint struct {
+	cfg        config.WebhookEndpoint
+	secret     []byte
+	stages     []string
+	client     *http.Client
+	maxAttempt int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	js         nats.JetStreamContext
+	log        log.Logger
+}
+
+// Run subscribes every configured endpoint and dispatches until ctx ends.
+func Run(ctx context.Context, cfg *config.Config, logger log.Logger) error {
+	wc := cfg.Webhooks
+	if len(wc.Endpoints) == 0 {
+		return fmt.Errorf("webhook: no endpoints configured")
+	}
+	nc, err := natsjs.Connect(ctx, cfg.NATS.URLs, nats.Name(connName))
+	if err != nil {
+		return err
+	}
+	js, err := natsjs.JetStream(nc)
+	if err != nil {
+		return err
+	}
+	if err = natsjs.Bootstrap(js, cfg.HTTP.IdempotencyTTL()); err != nil {
+		return err
+	}
+
+	// endpoint names and urls are validated by config.Load
+	for _, ec := range wc.Endpoints {
+		ep := &endpoint{
+			cfg:        ec,
+			secret:     []byte(ec.Secret),
+			stages:     ec.Stages,
+			client:     &http.Client{Timeout: msOr(ec.TimeoutMS, defaultTimeout)},
+			maxAttempt: wc.MaxAttempts,
+			backoff:    msOr(wc.BackoffMS, defaultBackoff),
+			maxBackoff: msOr(wc.MaxBackoffMS, defaultMaxBackoff),
+			js:         js,
+			log:        logger,
+		}
+		if len(ep.stages) == 0 {
+			ep.stages = defaultStages
+		}
+		if ep.maxAttempt <= 0 {
+			ep.maxAttempt = defaultMaxAttempts
+		}
+		// the consumer is created here rather than by the subscription, so it
+		// and its pending retries outlive restarts
+		group := fmt.Sprintf("%s-%s", connName, ec.Name)
+		logger.Info("subscribing", "subject", natsjs.SubjDecisionsAll, "group", group, "url", ec.URL)
+		if err := natsjs.EnsureDurableConsumer(js, natsjs.StreamDecisions, group, natsjs.SubjDecisionsAll, ep.client.Timeout+30*time.Second, maxAckPending); err != nil {
+			return err
+		}
+		sub, err := natsjs.SubscribeBound(ctx, js, natsjs.StreamDecisions, group, natsjs.SubjDecisionsAll, ep.handle)
+		if err != nil {
+			return err
+		}
+		defer sub.Unsubscribe()
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func (ep *endpoint) handle(m *nats.Msg) {
+	var de events.DecisionEvent
+	if err := de.Unmarshal(m.Data); err != nil {
+		ep.log.Error("webhook unmarshal", "endpoint", ep.cfg.Name, "err", err)
+		ep.deadLetter(m, 1, err)
+		return
+	}
+	if !ep.wants(&de) {
+		_ = m.Ack()
+		return
+	}
+	attempt := 1
+	if md, err := m.Metadata(); err == nil {
+		attempt = int(md.NumDelivered)
+	}
+
+	err := ep.deliver(&de, m.Data, attempt)
+	switch {
+	case err == nil:
+		ep.log.Info("webhook delivered", "endpoint", ep.cfg.Name, "decision_id", de.DecisionID, "attempt", attempt)
+		_ = m.Ack()
+	case isPermanent(err) || attempt >= ep.maxAttempt:
+		ep.log.Error("webhook failed", "endpoint", ep.cfg.Name, "decision_id", de.DecisionID, "attempt", attempt, "err", err)
+		ep.deadLetter(m, attempt, err)
+	default:
+		delay := ep.backoffFor(attempt)
+		ep.log.Warn("webhook retry", "endpoint", ep.cfg.Name, "decision_id", de.DecisionID, "attempt", attempt, "in", delay, "err", err)
+		_ = m.NakWithDelay(delay)
+	}
+}
+
+// wants applies the endpoint's stage and decision filters.
+func (ep *endpoint) wants(de *events.DecisionEvent) bool {
+	if !slices.Contains(ep.stages, de.Stage) {
+		return false
+	}
+	return len(ep.cfg.Decisions) == 0 || slices.Contains(ep.cfg.Decisions, de.Decision)
+}
+
+func (ep *endpoint) deliver(de *events.DecisionEvent, body []byte, attempt int) error {
+	req, err := http.NewRequest(http.MethodPost, ep.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return permanent{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDecisionID, de.DecisionID)
+	req.Header.Set(HeaderAttempt, strconv.Itoa(attempt))
+	if len(ep.secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(ep.secret, time.Now(), body))
+	}
+	resp, err := ep.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	err = fmt.Errorf("endpoint returned %s", resp.Status)
+	// the endpoint rejected the payload itself; retrying will not help
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return permanent{err}
+	}
+	return err
+}
+
+// backoffFor doubles the base delay per failed attempt, up to the maximum.
+func (ep *endpoint) backoffFor(attempt int) time.Duration {
+	d := ep.backoff
+	for i := 1; i < attempt && d < ep.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, ep.maxBackoff)
+}
+
+// deadLetter parks the message on the dead-letter stream and acks it; if the
+// DLQ publish fails the message is left for redelivery.
+func (ep *endpoint) deadLetter(m *nats.Msg, attempts int, cause error) {
+	dl := &nats.Msg{Subject: natsjs.SubjWebhookDLQ + "." + ep.cfg.Name, Data: m.Data, Header: nats.Header{}}
+	dl.Header.Set(nats.MsgIdHdr, dlqMsgID(ep.cfg.Name, m))
+	dl.Header.Set("Riskr-Endpoint", ep.cfg.Name)
+	dl.Header.Set("Riskr-Source-Subject", m.Subject)
+	dl.Header.Set("Riskr-Attempts", strconv.Itoa(attempts))
+	dl.Header.Set("Riskr-Error", cause.Error())
+	if _, err := ep.js.PublishMsg(dl); err != nil {
+		ep.log.Error("webhook dead-letter", "endpoint", ep.cfg.Name, "err", err)
+		_ = m.NakWithDelay(ep.maxBackoff)
+		return
+	}
+	_ = m.Ack()
+}
+
+// dlqMsgID identifies the dead letter of m for the endpoint, so a DLQ publish
+// repeated after its ack was lost is stored once.
+func dlqMsgID(endpoint string, m *nats.Msg) string {
+	if md, err := m.Metadata(); err == nil {
+		return fmt.Sprintf("%s-%d", endpoint, md.Sequence.Stream)
+	}
+	h := sha256.Sum256(m.Data)
+	return endpoint + "-" + hex.EncodeToString(h[:8])
+}
+
+// Sign returns the signature header value for body sent at t.
+func Sign(secret []byte, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type permanent struct{ error }
+
+func isPermanent(err error) bool {
+	_, ok := err.(permanent)
+	return ok
+}
+
+func msOr(ms int, def time.Duration) time.Duration {
+	if ms <= 0 {
+		return def
+	}
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/christophercampbell/riskr/pkg/config"
+	"github.com/christophercampbell/riskr/pkg/events"
+)
+
+func TestSign(t *testing.T) {
+	// printf '1700000000.{"decision_id":"d1"}' | openssl dgst -sha256 -hmac secret
+	got := Sign([]byte("secret"), time.Unix(1700000000, 0), []byte(`{"decision_id":"d1"}`))
+	want := "t=1700000000,v1=e39a32fccb0cb54b5b99b2fda2d1120cab20774a4ab4fbb4be19297752523446"
+	if got != want {
+		t.Fatalf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	ep := &endpoint{backoff: time.Second, maxBackoff: time.Minute}
+	for attempt, want := range map[int]time.Duration{
+		1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 6: 32 * time.Second, 7: time.Minute, 100: time.Minute,
+	} {
+		if got := ep.backoffFor(attempt); got != want {
+			t.Errorf("backoffFor(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestWants(t *testing.T) {
+	all := &endpoint{stages: defaultStages}
+	held := &endpoint{stages: []string{"provisional", "final"}, cfg: config.WebhookEndpoint{Decisions: []string{"HOLD_AUTO", "REJECT_FATAL"}}}
+	for _, tc := range []struct {
+		ep              *endpoint
+		stage, decision string
+		want            bool
+	}{
+		{all, "provisional", "ALLOW", false}, // returned synchronously by the gateway
+		{all, "final", "ALLOW", true},
+		{all, "override", "REVIEW", true},
+		{held, "provisional", "HOLD_AUTO", true},
+		{held, "final", "REJECT_FATAL", true},
+		{held, "final", "ALLOW", false},
+		{held, "override", "HOLD_AUTO", false},
+	} {
+		if got := tc.ep.wants(&events.DecisionEvent{Stage: tc.stage, Decision: tc.decision}); got != tc.want {
+			t.Errorf("stages %v decisions %v: wants(%s %s) = %v", tc.ep.stages, tc.ep.cfg.Decisions, tc.stage, tc.decision, got)
+		}
+	}
+}
+
+func TestDLQMsgID(t *testing.T) {
+	// a JetStream delivery: the ID follows the stream sequence
+	m := &nats.Msg{Sub: &nats.Subscription{}, Reply: "$JS.ACK.DECISIONS.c.3.42.7.1700000000000000000.0", Data: []byte("a")}
+	if got := dlqMsgID("ledger", m); got != "ledger-42" {
+		t.Fatalf("dlqMsgID = %s, want ledger-42", got)
+	}
+	// otherwise the data identifies it
+	a, b := &nats.Msg{Data: []byte("a")}, &nats.Msg{Data: []byte("b")}
+	if dlqMsgID("ledger", a) != dlqMsgID("ledger", &nats.Msg{Data: []byte("a")}) || dlqMsgID("ledger", a) == dlqMsgID("ledger", b) || dlqMsgID("ledger", a) == dlqMsgID("crm", a) {
+		t.Fatal("dlqMsgID not derived from endpoint and data")
+	}
+}